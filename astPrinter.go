@@ -0,0 +1,193 @@
+package golox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AstPrinter renders expressions and statements as Lisp-like s-expressions.
+// The visitor methods return the rendered text as a string Value.
+type AstPrinter struct {
+}
+
+func (ap *AstPrinter) Print(expr Expr) string {
+	res, _ := expr.Accept(ap)
+
+	return res.AsString()
+}
+
+func (ap *AstPrinter) PrintStmt(stmt Stmt) string {
+	res, _ := stmt.Accept(ap)
+
+	return res.AsString()
+}
+
+// Expressions
+
+func (ap *AstPrinter) AcceptAssignExpr(a *Assign) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("= "+a.Name.Lexeme, a.Value)), nil
+}
+
+func (ap *AstPrinter) AcceptBinaryExpr(b *Binary) (Value, *LoxError) {
+	return StringValue(ap.parenthesize(b.Operator.Lexeme, b.Left, b.Right)), nil
+}
+
+func (ap *AstPrinter) AcceptGroupingExpr(g *Grouping) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("group", g.Expr)), nil
+}
+
+func (ap *AstPrinter) AcceptLiteralExpr(l *Literal) (Value, *LoxError) {
+	if l.Value.IsString() {
+		return StringValue(strconv.Quote(l.Value.AsString())), nil
+	}
+
+	return StringValue(Stringify(l.Value)), nil
+}
+
+func (ap *AstPrinter) AcceptUnaryExpr(u *Unary) (Value, *LoxError) {
+	if u.Operator.Type == INTERPOLATION {
+		return StringValue(ap.parenthesize("str", u.Right)), nil
+	}
+
+	return StringValue(ap.parenthesize(u.Operator.Lexeme, u.Right)), nil
+}
+
+func (ap *AstPrinter) AcceptCallExpr(c *Call) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("call", append([]Expr{c.Callee}, c.Args...)...)), nil
+}
+
+func (ap *AstPrinter) AcceptVariableExpr(v *Variable) (Value, *LoxError) {
+	return StringValue(v.Name.Lexeme), nil
+}
+
+func (ap *AstPrinter) AcceptLogicalExpr(l *Logical) (Value, *LoxError) {
+	return StringValue(ap.parenthesize(l.Operator.Lexeme, l.Left, l.Right)), nil
+}
+
+func (ap *AstPrinter) AcceptConditionalExpr(c *Conditional) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("?:", c.Condition, c.Then, c.Else)), nil
+}
+
+func (ap *AstPrinter) AcceptCompoundAssignExpr(c *CompoundAssign) (Value, *LoxError) {
+	return StringValue(ap.parenthesize(c.Operator.Lexeme+" "+c.Name.Lexeme, c.Value)), nil
+}
+
+func (ap *AstPrinter) AcceptIncrementExpr(i *Increment) (Value, *LoxError) {
+	if i.Postfix {
+		return StringValue(fmt.Sprintf("(postfix%s %s)", i.Operator.Lexeme, i.Name.Lexeme)), nil
+	}
+
+	return StringValue(fmt.Sprintf("(%s %s)", i.Operator.Lexeme, i.Name.Lexeme)), nil
+}
+
+func (ap *AstPrinter) AcceptGetExpr(g *Get) (Value, *LoxError) {
+	return StringValue(fmt.Sprintf("(. %s %s)", ap.Print(g.Object), g.Name.Lexeme)), nil
+}
+
+func (ap *AstPrinter) AcceptLambdaExpr(l *Lambda) (Value, *LoxError) {
+	return StringValue(ap.parenthesizeStmts(fmt.Sprintf("lambda(%s)", joinParams(l.Decl.Params)), l.Decl.Body...)), nil
+}
+
+// Statements
+
+func (ap *AstPrinter) AcceptBlockStmt(b *Block) (Value, *LoxError) {
+	return StringValue(ap.parenthesizeStmts("block", b.Stmts...)), nil
+}
+
+func (ap *AstPrinter) AcceptExpressionStmt(e *Expression) (Value, *LoxError) {
+	return StringValue(ap.parenthesize(";", e.Expr)), nil
+}
+
+func (ap *AstPrinter) AcceptPrintStmt(p *Print) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("print", p.Expr)), nil
+}
+
+func (ap *AstPrinter) AcceptVarStmt(v *Var) (Value, *LoxError) {
+	if v.Initializer == nil {
+		return StringValue(fmt.Sprintf("(var %s)", v.Name.Lexeme)), nil
+	}
+
+	return StringValue(ap.parenthesize("var "+v.Name.Lexeme+" =", v.Initializer)), nil
+}
+
+func (ap *AstPrinter) AcceptFuncStmt(f *Func) (Value, *LoxError) {
+	return StringValue(ap.parenthesizeStmts(fmt.Sprintf("fun %s(%s)", f.Name.Lexeme, joinParams(f.Params)), f.Body...)), nil
+}
+
+func joinParams(params []Token) string {
+	names := make([]string, 0, len(params))
+	for _, p := range params {
+		names = append(names, p.Lexeme)
+	}
+
+	return strings.Join(names, " ")
+}
+
+func (ap *AstPrinter) AcceptIfStmt(i *If) (Value, *LoxError) {
+	if i.ElseBody == nil {
+		return StringValue(fmt.Sprintf("(if %s %s)", ap.Print(i.Condition), ap.PrintStmt(i.Body))), nil
+	}
+
+	return StringValue(fmt.Sprintf("(if-else %s %s %s)", ap.Print(i.Condition), ap.PrintStmt(i.Body), ap.PrintStmt(i.ElseBody))), nil
+}
+
+func (ap *AstPrinter) AcceptWhileStmt(w *While) (Value, *LoxError) {
+	return StringValue(fmt.Sprintf("(while %s %s)", ap.Print(w.Condition), ap.PrintStmt(w.Body))), nil
+}
+
+func (ap *AstPrinter) AcceptReturnStmt(r *Return) (Value, *LoxError) {
+	if r.Value == nil {
+		return StringValue("(return)"), nil
+	}
+
+	return StringValue(ap.parenthesize("return", r.Value)), nil
+}
+
+func (ap *AstPrinter) AcceptThrowStmt(t *Throw) (Value, *LoxError) {
+	return StringValue(ap.parenthesize("throw", t.Value)), nil
+}
+
+func (ap *AstPrinter) AcceptTryStmt(t *Try) (Value, *LoxError) {
+	res := strings.TrimSuffix(ap.parenthesizeStmts("try", t.Body...), ")")
+
+	if t.CatchBody != nil {
+		res += " " + ap.parenthesizeStmts("catch "+t.CatchName.Lexeme, t.CatchBody...)
+	}
+
+	if t.FinallyBody != nil {
+		res += " " + ap.parenthesizeStmts("finally", t.FinallyBody...)
+	}
+
+	return StringValue(res + ")"), nil
+}
+
+func (ap *AstPrinter) parenthesize(name string, exprs ...Expr) string {
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("(%s", name))
+
+	for _, expr := range exprs {
+		sb.WriteString(" ")
+		sb.WriteString(ap.Print(expr))
+	}
+
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+func (ap *AstPrinter) parenthesizeStmts(name string, stmts ...Stmt) string {
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("(%s", name))
+
+	for _, stmt := range stmts {
+		sb.WriteString(" ")
+		sb.WriteString(ap.PrintStmt(stmt))
+	}
+
+	sb.WriteString(")")
+
+	return sb.String()
+}
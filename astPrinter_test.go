@@ -1,6 +1,7 @@
 package golox_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/agayev169/golox"
@@ -20,7 +21,7 @@ var astPrinterTestData = map[string]printerTestDto{
 					Lexeme: "-",
 				},
 				Right: &golox.Literal{
-					Value: 123,
+					Value: golox.NumberValue(123),
 				},
 			},
 			Operator: golox.Token{
@@ -29,7 +30,7 @@ var astPrinterTestData = map[string]printerTestDto{
 			},
 			Right: &golox.Grouping{
 				Expr: &golox.Literal{
-					Value: 45.67,
+					Value: golox.NumberValue(45.67),
 				},
 			},
 		},
@@ -38,7 +39,7 @@ var astPrinterTestData = map[string]printerTestDto{
 }
 
 func TestPrinter(t *testing.T) {
-	ap := &AstPrinter{}
+	ap := &golox.AstPrinter{}
 	for k, tv := range astPrinterTestData {
 		actual, err := tv.Expression.Accept(ap)
 
@@ -46,13 +47,45 @@ func TestPrinter(t *testing.T) {
 			t.Fatalf("Failed on test %s. Expression.Accept return non-nil error: %v\n", k, err.Error())
 		}
 
-		switch v := actual.(type) {
-		case string:
-			if tv.Expected != v {
-				t.Fatalf("Failed on test %s. Expected: %s, got: %s\n", k, tv.Expected, actual)
-			}
-		default:
-			t.Fatalf("Expected the output of expr.Accept(ap) to be string, received %v\n", v)
+		if !actual.IsString() {
+			t.Fatalf("Expected the output of expr.Accept(ap) to be string, received %v\n", actual)
+		}
+
+		if tv.Expected != actual.AsString() {
+			t.Fatalf("Failed on test %s. Expected: %s, got: %s\n", k, tv.Expected, actual)
+		}
+	}
+}
+
+var astPrinterStmtTestData = map[string]string{
+	"var a = 1; print a;":             "(var a = 1) (print a)",
+	"fun f(a, b) { return a + b; }":   "(fun f(a b) (return (+ a b)))",
+	"if (a) b = \"x\"; else { c(); }": "(if-else a (; (= b \"x\")) (block (; (call c))))",
+	"while (a or b) print \"${a}!\";": "(while (or a b) (print (+ (+ \"\" (str a)) \"!\")))",
+	"var f = (a) => a * 2;":           "(var f = (lambda(a) (return (* a 2))))",
+	"f(fun (a, b) { print a; });":     "(; (call f (lambda(a b) (print a))))",
+	"print (a) + (b);":                "(print (+ (group a) (group b)))",
+	"a += b ? 1 : c % 2;":             "(; (+= a (?: b 1 (% c 2))))",
+	"f((a, b), c = d, e);":            "(; (call f (group (, a b)) (= c d) e))",
+	"print i++ - --j;":                "(print (- (postfix++ i) (-- j)))",
+	"try { throw e.message; } catch (e) { print e; } finally { f(); }": "(try (throw (. e message)) (catch e (print e)) (finally (; (call f))))",
+}
+
+func TestPrinterStmts(t *testing.T) {
+	ap := &golox.AstPrinter{}
+	for src, expected := range astPrinterStmtTestData {
+		stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(src))).Parse()
+		if err != nil {
+			t.Fatalf("Failed on %q. Got error on p.Parse(): %v", src, err)
+		}
+
+		actual := make([]string, 0, len(stmts))
+		for _, stmt := range stmts {
+			actual = append(actual, ap.PrintStmt(stmt))
+		}
+
+		if strings.Join(actual, " ") != expected {
+			t.Fatalf("Failed on %q. Expected: %s, got: %s", src, expected, strings.Join(actual, " "))
 		}
 	}
 }
@@ -0,0 +1,129 @@
+package golox
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"time"
+)
+
+// PhaseStats is the cost of running one phase of the pipeline.
+type PhaseStats struct {
+	Duration time.Duration
+	Allocs   uint64
+	Bytes    uint64
+}
+
+func (s *PhaseStats) add(o PhaseStats) {
+	s.Duration += o.Duration
+	s.Allocs += o.Allocs
+	s.Bytes += o.Bytes
+}
+
+// BenchResult holds the average cost of each phase over Runs runs of a script.
+type BenchResult struct {
+	Path     string
+	Runs     int
+	Scan     PhaseStats
+	Parse    PhaseStats
+	Resolve  PhaseStats
+	Optimize PhaseStats
+	Run      PhaseStats
+}
+
+func (r *BenchResult) Total() PhaseStats {
+	res := PhaseStats{}
+	for _, s := range []PhaseStats{r.Scan, r.Parse, r.Resolve, r.Optimize, r.Run} {
+		res.add(s)
+	}
+
+	return res
+}
+
+// RunBenchmark scans, parses, resolves, optimizes and interprets the script at
+// path runs times, discarding its output, and measures every phase separately.
+func RunBenchmark(path string, runs int) (*BenchResult, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BenchResult{Path: path, Runs: runs}
+
+	for i := 0; i < runs; i++ {
+		if err = benchmarkOnce(src, res); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range []*PhaseStats{&res.Scan, &res.Parse, &res.Resolve, &res.Optimize, &res.Run} {
+		s.Duration /= time.Duration(runs)
+		s.Allocs /= uint64(runs)
+		s.Bytes /= uint64(runs)
+	}
+
+	return res, nil
+}
+
+func benchmarkOnce(src []byte, res *BenchResult) error {
+	var tokens []Token
+	var stmts []Stmt
+	var err error
+	var lerr *LoxError
+
+	res.Scan.add(measure(func() {
+		tokens, err = NewScanner(bytes.NewReader(src)).ScanTokens()
+	}))
+	if err != nil {
+		return err
+	}
+
+	res.Parse.add(measure(func() {
+		stmts, lerr = NewParser(tokens).Parse()
+	}))
+	if lerr != nil {
+		return lerr
+	}
+
+	interp := NewInterpreter()
+	interp.SetOutput(io.Discard)
+
+	res.Resolve.add(measure(func() {
+		lerr = NewResolver(interp).Resolve(stmts)
+	}))
+	if lerr != nil {
+		return lerr
+	}
+
+	res.Optimize.add(measure(func() {
+		stmts = NewOptimizer().Optimize(stmts)
+	}))
+
+	res.Run.add(measure(func() {
+		_, lerr = interp.Interpret(stmts)
+	}))
+	if lerr != nil {
+		return lerr
+	}
+
+	return nil
+}
+
+func measure(f func()) PhaseStats {
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	f()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return PhaseStats{
+		Duration: elapsed,
+		Allocs:   after.Mallocs - before.Mallocs,
+		Bytes:    after.TotalAlloc - before.TotalAlloc,
+	}
+}
@@ -0,0 +1,107 @@
+package golox_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+// Each benchmark runs one phase of the pipeline on every script in the
+// benchmarks directory, e.g. BenchmarkInterpret/fib.
+
+type benchScript struct {
+	Name   string
+	Src    []byte
+	Tokens []golox.Token
+	Stmts  []golox.Stmt
+}
+
+func loadBenchScripts(b *testing.B) []benchScript {
+	paths, err := filepath.Glob(filepath.Join("benchmarks", "*.lox"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	res := make([]benchScript, 0, len(paths))
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		tokens, err := golox.NewScanner(bytes.NewReader(src)).ScanTokens()
+		if err != nil {
+			b.Fatalf("Failed on %s. Got error on s.ScanTokens(): %v", path, err)
+		}
+
+		stmts, lerr := golox.NewParser(tokens).Parse()
+		if lerr != nil {
+			b.Fatalf("Failed on %s. Got error on p.Parse(): %v", path, lerr)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), ".lox")
+		res = append(res, benchScript{Name: name, Src: src, Tokens: tokens, Stmts: stmts})
+	}
+
+	return res
+}
+
+func runBenchScripts(b *testing.B, f func(b *testing.B, s benchScript)) {
+	for _, s := range loadBenchScripts(b) {
+		s := s
+		b.Run(s.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f(b, s)
+			}
+		})
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	runBenchScripts(b, func(b *testing.B, s benchScript) {
+		if _, err := golox.NewScanner(bytes.NewReader(s.Src)).ScanTokens(); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	runBenchScripts(b, func(b *testing.B, s benchScript) {
+		if _, err := golox.NewParser(s.Tokens).Parse(); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkResolve(b *testing.B) {
+	runBenchScripts(b, func(b *testing.B, s benchScript) {
+		if err := golox.NewResolver(golox.NewInterpreter()).Resolve(s.Stmts); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+func BenchmarkInterpret(b *testing.B) {
+	runBenchScripts(b, func(b *testing.B, s benchScript) {
+		b.StopTimer()
+		interp := golox.NewInterpreter()
+		interp.SetOutput(io.Discard)
+
+		if err := golox.NewResolver(interp).Resolve(s.Stmts); err != nil {
+			b.Fatal(err)
+		}
+
+		stmts := golox.NewOptimizer().Optimize(s.Stmts)
+		b.StartTimer()
+
+		if _, err := interp.Interpret(stmts); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
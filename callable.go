@@ -1,14 +1,6 @@
 package golox
 
-import (
-	"fmt"
-	"time"
-)
-
-type Callable interface {
-	GetArity() int
-	Call(i *Interpreter, args []interface{}) (interface{}, *LoxError)
-}
+import "fmt"
 
 // Custom
 
@@ -22,33 +14,65 @@ const (
 type LoxFunction struct {
 	decl    *Func
 	closure *Env
+	// The interpreter running the function when it is called as a Callable.
+	interp *Interpreter
 }
 
-func NewLoxFunction(decl *Func, closure *Env) *LoxFunction {
-	return &LoxFunction{decl: decl, closure: closure}
+func NewLoxFunction(decl *Func, closure *Env, interp *Interpreter) *LoxFunction {
+	return &LoxFunction{decl: decl, closure: closure, interp: interp}
 }
 
 func (f *LoxFunction) GetArity() int {
 	return len(f.decl.Params)
 }
 
-func (f *LoxFunction) Call(i *Interpreter, args []interface{}) (interface{}, *LoxError) {
-	return f.call(i, args)
+func (f *LoxFunction) Call(args []Value) (Value, *LoxError) {
+	return f.call(f.interp, args)
+}
+
+// call runs the function and then, as long as it returns with a tail call,
+// the function it calls, so that tail calls run in constant Go stack.
+func (f *LoxFunction) call(i *Interpreter, args []Value) (Value, *LoxError) {
+	for {
+		ret, tail, err := f.callOnce(i, args)
+		if err != nil || tail == nil {
+			return ret, err
+		}
+
+		f, args = tail.Fn, tail.Args
+
+		if n := len(i.frames); n > 0 {
+			i.frames[n-1].Name = f.decl.Name.Lexeme
+		}
+	}
 }
 
-func (f *LoxFunction) call(i *Interpreter, args []interface{}) (ret interface{}, err *LoxError) {
+func (f *LoxFunction) callOnce(i *Interpreter, args []Value) (ret Value, tail *Control, err *LoxError) {
 	defer func() {
 		if r := recover(); r != nil {
 			c, ok := r.(*Control)
-			if !ok || c.Type != Ret {
+			if !ok {
 				panic(r)
 			}
 
-			ret = c.Val
+			if c.Type == TailCall {
+				tail = c
+			} else {
+				ret = c.Val
+			}
 		}
 	}()
 
-	ret, err = nil, nil
+	if i.profiler != nil {
+		i.profiler.enterFunc(f.decl)
+		defer i.profiler.leaveFunc()
+	}
+
+	if i.coverage != nil {
+		i.coverage.hitFunc(f.decl)
+	}
+
+	ret, err = NilValue, nil
 
 	env := NewEnv(f.closure)
 
@@ -69,19 +93,3 @@ func (f *LoxFunction) call(i *Interpreter, args []interface{}) (ret interface{},
 func (f *LoxFunction) String() string {
 	return fmt.Sprintf("<fn %s>", f.decl.Name.Lexeme)
 }
-
-// Clock
-
-type LoxClock struct{}
-
-func (l *LoxClock) GetArity() int {
-	return 0
-}
-
-func (l *LoxClock) Call(*Interpreter, []interface{}) (interface{}, *LoxError) {
-	return float64(time.Now().UnixMilli()) / 1000.0, nil
-}
-
-func (l *LoxClock) String() string {
-	return "<native fn>"
-}
@@ -0,0 +1,810 @@
+package golox
+
+import (
+	"fmt"
+	"strings"
+)
+
+type typeKind uint8
+
+const (
+	anyKind typeKind = iota
+	nilKind
+	boolKind
+	// Any number, integer or floating-point.
+	numKind
+	intKind
+	strKind
+	funKind
+)
+
+var typeKindNames = map[typeKind]string{
+	anyKind:  "any",
+	nilKind:  "nil",
+	boolKind: "bool",
+	numKind:  "num",
+	intKind:  "int",
+	strKind:  "str",
+	funKind:  "fun",
+}
+
+// staticType is the type the Checker infers for an expression.
+type staticType struct {
+	kind typeKind
+	// The signature of a function type. params is nil if it is unknown.
+	params []*staticType
+	ret    *staticType
+}
+
+var (
+	anyT  = &staticType{kind: anyKind}
+	nilT  = &staticType{kind: nilKind}
+	boolT = &staticType{kind: boolKind}
+	numT  = &staticType{kind: numKind}
+	intT  = &staticType{kind: intKind}
+	strT  = &staticType{kind: strKind}
+	funT  = &staticType{kind: funKind}
+)
+
+var namedTypes = map[string]*staticType{
+	"any":  anyT,
+	"bool": boolT,
+	"num":  numT,
+	"int":  intT,
+	"str":  strT,
+}
+
+func (t *staticType) String() string {
+	if t.kind != funKind || t.params == nil {
+		return typeKindNames[t.kind]
+	}
+
+	params := make([]string, 0, len(t.params))
+	for _, p := range t.params {
+		params = append(params, p.String())
+	}
+
+	return fmt.Sprintf("fun(%s): %s", strings.Join(params, ", "), t.ret)
+}
+
+func (t *staticType) isNumber() bool {
+	return t.kind == numKind || t.kind == intKind
+}
+
+// assignable reports whether a value of type from can be used where a value
+// of type to is expected. Values of type any can be used anywhere, and
+// anything can be used where any is expected.
+func assignable(to, from *staticType) bool {
+	switch {
+	case to.kind == anyKind || from.kind == anyKind:
+		return true
+	case to.kind == numKind && from.kind == intKind:
+		return true
+	case to.kind != from.kind:
+		return false
+	case to.kind != funKind || to.params == nil || from.params == nil:
+		return true
+	case len(to.params) != len(from.params):
+		return false
+	}
+
+	for i, p := range to.params {
+		if !assignable(from.params[i], p) {
+			return false
+		}
+	}
+
+	return assignable(to.ret, from.ret)
+}
+
+// join returns the most precise type of a value that is either of type a or
+// of type b.
+func join(a, b *staticType) *staticType {
+	switch {
+	case a.kind == anyKind || b.kind == anyKind:
+		return anyT
+	case assignable(a, b) && assignable(b, a):
+		return a
+	case a.isNumber() && b.isNumber():
+		return numT
+	case a.kind == funKind && b.kind == funKind:
+		return funT
+	}
+
+	return anyT
+}
+
+func literalType(v Value) *staticType {
+	switch v.Type() {
+	case NilType:
+		return nilT
+	case BoolType:
+		return boolT
+	case NumberType:
+		return numT
+	case IntType:
+		return intT
+	case StringType:
+		return strT
+	}
+
+	return anyT
+}
+
+type checkVar struct {
+	typ *staticType
+	// Assignments to annotated variables must keep to their type.
+	annotated bool
+}
+
+// checkFunc is the function whose body is being checked.
+type checkFunc struct {
+	// The annotated return type, or nil.
+	ret     *staticType
+	returns []*staticType
+}
+
+// Checker is a static type checker for programs with optional type
+// annotations. It infers the types of expressions from literals, annotations
+// and the initializers of unannotated variables that are never reassigned,
+// and reports the operators, calls, assignments and returns that would fail
+// or break an annotation at run time. Anything it cannot infer, such as an
+// unannotated variable assigned anywhere in the program, has type any and is
+// never reported.
+//
+// The Checker is a visitor whose expression methods return the inferred type
+// wrapped in an object Value.
+type Checker struct {
+	// The global scope comes first.
+	scopes []map[string]*checkVar
+	fn     *checkFunc
+	errs   []*LoxError
+	// The declarations of the variables assigned anywhere in the program.
+	assigned *assignedVars
+}
+
+func NewChecker() *Checker {
+	globals := map[string]*checkVar{
+		"clock": {typ: &staticType{kind: funKind, params: []*staticType{}, ret: numT}, annotated: true},
+		"len":   {typ: &staticType{kind: funKind, params: []*staticType{strT}, ret: intT}, annotated: true},
+	}
+
+	return &Checker{scopes: []map[string]*checkVar{globals}}
+}
+
+// Check checks a resolved program and returns the errors found, in the order
+// they were found.
+func (c *Checker) Check(stmts []Stmt) []*LoxError {
+	c.assigned = findAssignedVars(stmts)
+	c.checkStmts(stmts)
+
+	return c.errs
+}
+
+// Expressions
+
+func (c *Checker) AcceptAssignExpr(a *Assign) (Value, *LoxError) {
+	t := c.check(a.Value)
+	c.assign(a.Name, t)
+
+	return typeValue(t), nil
+}
+
+func (c *Checker) AcceptBinaryExpr(b *Binary) (Value, *LoxError) {
+	return typeValue(c.binary(b.Operator, c.check(b.Left), c.check(b.Right))), nil
+}
+
+func (c *Checker) AcceptGroupingExpr(g *Grouping) (Value, *LoxError) {
+	return typeValue(c.check(g.Expr)), nil
+}
+
+func (c *Checker) AcceptLiteralExpr(l *Literal) (Value, *LoxError) {
+	return typeValue(literalType(l.Value)), nil
+}
+
+func (c *Checker) AcceptUnaryExpr(u *Unary) (Value, *LoxError) {
+	t := c.check(u.Right)
+
+	switch u.Operator.Type {
+	case MINUS:
+		if t.kind != anyKind && !t.isNumber() {
+			c.errorf(u.Operator, "Operand of '-' must be a number, got %s.", t)
+
+			return typeValue(anyT), nil
+		}
+
+		return typeValue(t), nil
+	case BANG:
+		return typeValue(boolT), nil
+	case INTERPOLATION:
+		return typeValue(strT), nil
+	}
+
+	return typeValue(anyT), nil
+}
+
+func (c *Checker) AcceptCallExpr(call *Call) (Value, *LoxError) {
+	callee := c.check(call.Callee)
+
+	args := make([]*staticType, 0, len(call.Args))
+	for _, arg := range call.Args {
+		args = append(args, c.check(arg))
+	}
+
+	if callee.kind == anyKind {
+		return typeValue(anyT), nil
+	}
+
+	if callee.kind != funKind {
+		c.errorf(call.Paren, "Can only call functions, got %s.", callee)
+
+		return typeValue(anyT), nil
+	}
+
+	if callee.params == nil {
+		return typeValue(anyT), nil
+	}
+
+	if len(callee.params) != len(args) {
+		c.errorf(call.Paren, "Expected %d arguments but got %d.", len(callee.params), len(args))
+	} else {
+		for i, p := range callee.params {
+			if !assignable(p, args[i]) {
+				c.errorf(call.Paren, "Argument %d must be %s, got %s.", i+1, p, args[i])
+			}
+		}
+	}
+
+	return typeValue(callee.ret), nil
+}
+
+func (c *Checker) AcceptVariableExpr(v *Variable) (Value, *LoxError) {
+	if cv := c.lookUp(v.Name); cv != nil {
+		return typeValue(cv.typ), nil
+	}
+
+	return typeValue(anyT), nil
+}
+
+func (c *Checker) AcceptLogicalExpr(l *Logical) (Value, *LoxError) {
+	return typeValue(join(c.check(l.Left), c.check(l.Right))), nil
+}
+
+func (c *Checker) AcceptLambdaExpr(l *Lambda) (Value, *LoxError) {
+	t := c.signature(l.Decl)
+	c.checkFunction(l.Decl, t)
+
+	return typeValue(t), nil
+}
+
+func (c *Checker) AcceptGetExpr(g *Get) (Value, *LoxError) {
+	c.check(g.Object)
+
+	return typeValue(anyT), nil
+}
+
+func (c *Checker) AcceptConditionalExpr(cond *Conditional) (Value, *LoxError) {
+	c.check(cond.Condition)
+
+	return typeValue(join(c.check(cond.Then), c.check(cond.Else))), nil
+}
+
+func (c *Checker) AcceptCompoundAssignExpr(ca *CompoundAssign) (Value, *LoxError) {
+	cur := anyT
+	if cv := c.lookUp(ca.Name); cv != nil {
+		cur = cv.typ
+	}
+
+	op := ca.Operator
+	op.Type = compoundOperators[op.Type]
+
+	t := c.binary(op, cur, c.check(ca.Value))
+	c.assign(ca.Name, t)
+
+	return typeValue(t), nil
+}
+
+func (c *Checker) AcceptIncrementExpr(i *Increment) (Value, *LoxError) {
+	cv := c.lookUp(i.Name)
+	if cv == nil || cv.typ.kind == anyKind {
+		return typeValue(anyT), nil
+	}
+
+	if !cv.typ.isNumber() {
+		c.errorf(i.Operator, "Operand of '%s' must be a number, got %s.", i.Operator.Lexeme, cv.typ)
+
+		return typeValue(anyT), nil
+	}
+
+	return typeValue(cv.typ), nil
+}
+
+// Statements
+
+func (c *Checker) AcceptBlockStmt(b *Block) (Value, *LoxError) {
+	c.checkScope(b.Stmts)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptExpressionStmt(e *Expression) (Value, *LoxError) {
+	c.check(e.Expr)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptPrintStmt(p *Print) (Value, *LoxError) {
+	c.check(p.Expr)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptVarStmt(v *Var) (Value, *LoxError) {
+	t := anyT
+	if v.Initializer != nil {
+		t = c.check(v.Initializer)
+	}
+
+	if v.Type == nil {
+		c.declare(v.Name, &checkVar{typ: c.inferred(v.Name, t)})
+
+		return NilValue, nil
+	}
+
+	annotated := c.resolveType(v.Type)
+	if v.Initializer != nil && !assignable(annotated, t) {
+		c.errorf(v.Name, "Cannot initialize '%s' of type %s with %s.", v.Name.Lexeme, annotated, t)
+	}
+
+	c.declare(v.Name, &checkVar{typ: annotated, annotated: true})
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptFuncStmt(f *Func) (Value, *LoxError) {
+	t := c.signature(f)
+
+	// Declared first so that the function can call itself.
+	c.declare(f.Name, &checkVar{typ: c.inferred(f.Name, t)})
+	c.checkFunction(f, t)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptIfStmt(i *If) (Value, *LoxError) {
+	c.check(i.Condition)
+	c.checkStmt(i.Body)
+
+	if i.ElseBody != nil {
+		c.checkStmt(i.ElseBody)
+	}
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptWhileStmt(w *While) (Value, *LoxError) {
+	c.check(w.Condition)
+	c.checkStmt(w.Body)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptReturnStmt(r *Return) (Value, *LoxError) {
+	t := nilT
+	if r.Value != nil {
+		t = c.check(r.Value)
+	}
+
+	if c.fn == nil {
+		return NilValue, nil
+	}
+
+	if c.fn.ret != nil && !assignable(c.fn.ret, t) {
+		c.errorf(r.Keyword, "Expected to return %s, got %s.", c.fn.ret, t)
+	}
+
+	c.fn.returns = append(c.fn.returns, t)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptThrowStmt(t *Throw) (Value, *LoxError) {
+	c.check(t.Value)
+
+	return NilValue, nil
+}
+
+func (c *Checker) AcceptTryStmt(t *Try) (Value, *LoxError) {
+	c.checkScope(t.Body)
+
+	if t.CatchBody != nil {
+		c.beginScope()
+		c.declare(t.CatchName, &checkVar{typ: anyT})
+		c.checkStmts(t.CatchBody)
+		c.endScope()
+	}
+
+	if t.FinallyBody != nil {
+		c.checkScope(t.FinallyBody)
+	}
+
+	return NilValue, nil
+}
+
+// binary returns the type of the binary operator op applied to values of
+// types l and r, reporting operands that can never be valid.
+func (c *Checker) binary(op Token, l, r *staticType) *staticType {
+	switch op.Type {
+	case MINUS, STAR, SLASH, TILDE_SLASH, PERCENT, GREATER, GREATER_EQUAL, LESS, LESS_EQUAL:
+		if (l.kind != anyKind && !l.isNumber()) || (r.kind != anyKind && !r.isNumber()) {
+			c.errorf(op, "Operands of '%s' must be numbers, got %s and %s.", op.Lexeme, l, r)
+
+			return anyT
+		}
+	}
+
+	switch op.Type {
+	case MINUS, STAR, PERCENT:
+		return arithmeticType(l, r)
+	case SLASH:
+		return numT
+	case TILDE_SLASH:
+		return intT
+	case GREATER, GREATER_EQUAL, LESS, LESS_EQUAL, EQUAL_EQUAL, BANG_EQUAL:
+		return boolT
+	case PLUS:
+		switch {
+		case l.kind == anyKind || r.kind == anyKind:
+			return anyT
+		case l.isNumber() && r.isNumber():
+			return arithmeticType(l, r)
+		case l.kind == strKind && r.kind == strKind:
+			return strT
+		}
+
+		c.errorf(op, "Operands of '+' must be two numbers or two strings, got %s and %s.", l, r)
+	case COMMA:
+		return r
+	}
+
+	return anyT
+}
+
+// arithmeticType is the type of the result of +, - and * on two numbers.
+func arithmeticType(l, r *staticType) *staticType {
+	switch {
+	case l.kind == anyKind || r.kind == anyKind:
+		return anyT
+	case l.kind == intKind && r.kind == intKind:
+		return intT
+	}
+
+	return numT
+}
+
+// signature returns the type of the function f from its annotations.
+func (c *Checker) signature(f *Func) *staticType {
+	t := &staticType{kind: funKind, params: make([]*staticType, 0, len(f.Params)), ret: anyT}
+
+	for i := range f.Params {
+		t.params = append(t.params, c.resolveType(paramType(f, i)))
+	}
+
+	if f.ReturnType != nil {
+		t.ret = c.resolveType(f.ReturnType)
+	}
+
+	return t
+}
+
+// checkFunction checks the body of f, whose type is t. Unless it is
+// annotated, the return type of t is inferred from the returned values.
+func (c *Checker) checkFunction(f *Func, t *staticType) {
+	fn := &checkFunc{}
+	if f.ReturnType != nil {
+		fn.ret = t.ret
+	}
+
+	enclosing := c.fn
+	c.fn = fn
+	defer func() {
+		c.fn = enclosing
+	}()
+
+	c.beginScope()
+	defer c.endScope()
+
+	for i, p := range f.Params {
+		c.declare(p, &checkVar{typ: t.params[i], annotated: paramType(f, i) != nil})
+	}
+
+	c.checkStmts(f.Body)
+
+	if fn.ret != nil {
+		return
+	}
+
+	ret := nilT
+	if len(fn.returns) > 0 {
+		ret = fn.returns[0]
+		for _, r := range fn.returns[1:] {
+			ret = join(ret, r)
+		}
+
+		// A body that does not end with a return can also return nil.
+		if len(f.Body) == 0 || !isReturn(f.Body[len(f.Body)-1]) {
+			ret = join(ret, nilT)
+		}
+	}
+
+	t.ret = ret
+}
+
+// paramType returns the annotation of the i-th parameter of f, or nil.
+func paramType(f *Func, i int) *TypeExpr {
+	if i < len(f.ParamTypes) {
+		return f.ParamTypes[i]
+	}
+
+	return nil
+}
+
+func isReturn(s Stmt) bool {
+	_, ok := s.(*Return)
+
+	return ok
+}
+
+// resolveType returns the type an annotation stands for, or any if there is
+// no annotation.
+func (c *Checker) resolveType(te *TypeExpr) *staticType {
+	if te == nil {
+		return anyT
+	}
+
+	switch te.Name.Type {
+	case NIL:
+		return nilT
+	case FUN:
+		if te.Params == nil {
+			return funT
+		}
+
+		t := &staticType{kind: funKind, params: make([]*staticType, 0, len(te.Params)), ret: c.resolveType(te.Return)}
+		for _, p := range te.Params {
+			t.params = append(t.params, c.resolveType(p))
+		}
+
+		return t
+	}
+
+	if t, ok := namedTypes[te.Name.Lexeme]; ok {
+		return t
+	}
+
+	c.errs = append(c.errs, genError(te.Name, InvalidType, fmt.Sprintf("Unknown type '%s'.", te.Name.Lexeme)))
+
+	return anyT
+}
+
+// assign records that a value of type t is assigned to the variable name.
+func (c *Checker) assign(name Token, t *staticType) {
+	cv := c.lookUp(name)
+	if cv == nil {
+		return
+	}
+
+	if cv.annotated && !assignable(cv.typ, t) {
+		c.errorf(name, "Cannot assign %s to '%s' of type %s.", t, name.Lexeme, cv.typ)
+	}
+}
+
+// inferred returns the type of the unannotated variable declared by name
+// with a value of type t. Variables assigned anywhere have type any, since
+// an assignment may run before any use, e.g. later in a loop or in a function
+// declared earlier.
+func (c *Checker) inferred(name Token, t *staticType) *staticType {
+	if c.assigned.has(name, len(c.scopes) == 1) {
+		return anyT
+	}
+
+	return t
+}
+
+func (c *Checker) lookUp(name Token) *checkVar {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if cv, ok := c.scopes[i][name.Lexeme]; ok {
+			return cv
+		}
+	}
+
+	return nil
+}
+
+func (c *Checker) declare(name Token, cv *checkVar) {
+	c.scopes[len(c.scopes)-1][name.Lexeme] = cv
+}
+
+func (c *Checker) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]*checkVar))
+}
+
+func (c *Checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *Checker) checkScope(stmts []Stmt) {
+	c.beginScope()
+	defer c.endScope()
+
+	c.checkStmts(stmts)
+}
+
+func (c *Checker) checkStmts(stmts []Stmt) {
+	for _, s := range stmts {
+		c.checkStmt(s)
+	}
+}
+
+func (c *Checker) checkStmt(s Stmt) {
+	_, _ = s.Accept(c)
+}
+
+func (c *Checker) check(e Expr) *staticType {
+	v, _ := e.Accept(c)
+
+	return v.AsObject().(*staticType)
+}
+
+func (c *Checker) errorf(t Token, format string, args ...interface{}) {
+	c.errs = append(c.errs, genError(t, TypeMismatch, fmt.Sprintf(format, args...)))
+}
+
+func typeValue(t *staticType) Value {
+	return ObjectValue(t)
+}
+
+// assignedVars holds the variables a program assigns. Local variables are
+// known by the token declaring them, global ones by their name since they
+// may be assigned before they are declared.
+type assignedVars struct {
+	locals  map[Token]bool
+	globals map[string]bool
+	// The local scopes being walked, mapping names to their declarations.
+	scopes []map[string]Token
+}
+
+// findAssignedVars returns the variables stmts assign, with `=`, a compound
+// assignment or an increment.
+func findAssignedVars(stmts []Stmt) *assignedVars {
+	a := &assignedVars{locals: make(map[Token]bool), globals: make(map[string]bool)}
+	a.stmts(stmts)
+
+	return a
+}
+
+// has reports whether the variable declared by name is assigned.
+func (a *assignedVars) has(name Token, global bool) bool {
+	if global {
+		return a.globals[name.Lexeme]
+	}
+
+	return a.locals[name]
+}
+
+func (a *assignedVars) stmts(ss []Stmt) {
+	for _, s := range ss {
+		a.stmt(s)
+	}
+}
+
+func (a *assignedVars) scope(ss []Stmt) {
+	a.scopes = append(a.scopes, make(map[string]Token))
+	a.stmts(ss)
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+func (a *assignedVars) stmt(s Stmt) {
+	switch st := s.(type) {
+	case *Block:
+		a.scope(st.Stmts)
+	case *Expression:
+		a.expr(st.Expr)
+	case *Print:
+		a.expr(st.Expr)
+	case *Var:
+		a.expr(st.Initializer)
+		a.declare(st.Name)
+	case *Func:
+		a.declare(st.Name)
+		a.function(st)
+	case *If:
+		a.expr(st.Condition)
+		a.stmt(st.Body)
+		a.stmt(st.ElseBody)
+	case *While:
+		a.expr(st.Condition)
+		a.stmt(st.Body)
+	case *Return:
+		a.expr(st.Value)
+	case *Throw:
+		a.expr(st.Value)
+	case *Try:
+		a.scope(st.Body)
+
+		if st.CatchBody != nil {
+			a.scopes = append(a.scopes, map[string]Token{st.CatchName.Lexeme: st.CatchName})
+			a.stmts(st.CatchBody)
+			a.scopes = a.scopes[:len(a.scopes)-1]
+		}
+
+		a.scope(st.FinallyBody)
+	}
+}
+
+func (a *assignedVars) function(f *Func) {
+	sc := make(map[string]Token, len(f.Params))
+	for _, p := range f.Params {
+		sc[p.Lexeme] = p
+	}
+
+	a.scopes = append(a.scopes, sc)
+	a.stmts(f.Body)
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+func (a *assignedVars) expr(e Expr) {
+	switch ex := e.(type) {
+	case *Assign:
+		a.expr(ex.Value)
+		a.assign(ex.Name)
+	case *Binary:
+		a.expr(ex.Left)
+		a.expr(ex.Right)
+	case *Grouping:
+		a.expr(ex.Expr)
+	case *Unary:
+		a.expr(ex.Right)
+	case *Call:
+		a.expr(ex.Callee)
+		for _, arg := range ex.Args {
+			a.expr(arg)
+		}
+	case *Logical:
+		a.expr(ex.Left)
+		a.expr(ex.Right)
+	case *Lambda:
+		a.function(ex.Decl)
+	case *Get:
+		a.expr(ex.Object)
+	case *Conditional:
+		a.expr(ex.Condition)
+		a.expr(ex.Then)
+		a.expr(ex.Else)
+	case *CompoundAssign:
+		a.expr(ex.Value)
+		a.assign(ex.Name)
+	case *Increment:
+		a.assign(ex.Name)
+	}
+}
+
+func (a *assignedVars) declare(name Token) {
+	if len(a.scopes) > 0 {
+		a.scopes[len(a.scopes)-1][name.Lexeme] = name
+	}
+}
+
+// assign records an assignment to the variable name, which is global unless
+// a local scope declares it.
+func (a *assignedVars) assign(name Token) {
+	for i := len(a.scopes) - 1; i >= 0; i-- {
+		if decl, ok := a.scopes[i][name.Lexeme]; ok {
+			a.locals[decl] = true
+
+			return
+		}
+	}
+
+	a.globals[name.Lexeme] = true
+}
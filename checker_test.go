@@ -0,0 +1,54 @@
+package golox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+var checkerTestData = map[string]string{
+	"var x: num = 1; var y: int = 2; var s: str = \"a\";": "",
+	"var s: str = 1;":                                   "Cannot initialize 's' of type str with int.",
+	"var n: int = 1 / 2;":                               "Cannot initialize 'n' of type int with num.",
+	"var x: num = 1; x = nil;":                          "Cannot assign nil to 'x' of type num.",
+	"var x = 1; x = \"a\"; print x - 1;":                "",
+	"print \"a\" - 1;":                                  "Operands of '-' must be numbers, got str and int.",
+	"print 1 + \"a\";":                                  "Operands of '+' must be two numbers or two strings, got int and str.",
+	"print -true;":                                      "Operand of '-' must be a number, got bool.",
+	"print len(\"a\") + len(1);":                        "Argument 1 must be str, got int.",
+	"print clock(1);":                                   "Expected 0 arguments but got 1.",
+	"print \"a\"();":                                    "Can only call functions, got str.",
+	"fun f(a: num): str { return a; }":                  "Expected to return str, got num.",
+	"fun f(a) { return a * 2; } print f(\"a\") + f(1);": "",
+	"fun f() { return 1; } var s: str = f();":           "Cannot initialize 's' of type str with int.",
+	"var f: fun(num): num = (a: int): int => a;":        "Cannot initialize 'f' of type fun(num): num with fun(int): int.",
+	"var g: fun(int): num = (a: num): int => a ~/ 1;":   "",
+	"var x: thing = 1;":                                 "Unknown type 'thing'.",
+	"fun fib(n: int): int { return n < 2 ? n : fib(n - 1) + fib(n - 2); }":                 "",
+	"var x = nil; for (var i = 0; i < 2; i = i + 1) { if (x != nil) print x + 1; x = i; }": "",
+	"fun init() { y = 5; } var y = nil; init(); print y + 1;":                              "",
+	"var s = \"a\"; { var s = 1; s = 2; } print s - 1;":                                    "Operands of '-' must be numbers, got str and int.",
+}
+
+func TestChecker(t *testing.T) {
+	for src, expected := range checkerTestData {
+		stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(src))).Parse()
+		if err != nil {
+			t.Fatalf("Failed on %q. Got error on p.Parse(): %v", src, err)
+		}
+
+		if err = golox.NewResolver(golox.NewInterpreter()).Resolve(stmts); err != nil {
+			t.Fatalf("Failed on %q. Got error on r.Resolve(): %v", src, err)
+		}
+
+		actual := make([]string, 0)
+		for _, err := range golox.NewChecker().Check(stmts) {
+			actual = append(actual, err.Msg)
+		}
+
+		if strings.Join(actual, " ") != expected {
+			t.Fatalf("Failed on %q. Expected: %s, got: %s", src, expected, strings.Join(actual, " "))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agayev169/golox"
+)
+
+// runBench runs every .lox file found under paths and prints the average
+// time and allocations of each phase of the pipeline.
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := fs.Int("runs", 3, "number of runs of each script to average over")
+	_ = fs.Parse(args)
+
+	if *runs < 1 {
+		fatal(fmt.Errorf("-runs must be positive, got %d", *runs))
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"benchmarks"}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "script\tphase\ttime\tallocs\tbytes")
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(path, ".lox") {
+				return err
+			}
+
+			res, err := golox.RunBenchmark(path, *runs)
+			if err != nil {
+				return err
+			}
+
+			phases := []struct {
+				name  string
+				stats golox.PhaseStats
+			}{
+				{"scan", res.Scan}, {"parse", res.Parse}, {"resolve", res.Resolve},
+				{"optimize", res.Optimize}, {"run", res.Run}, {"total", res.Total()},
+			}
+
+			for i, p := range phases {
+				name := ""
+				if i == 0 {
+					name = path
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n",
+					name, p.name, formatDuration(p.stats.Duration), p.stats.Allocs, p.stats.Bytes)
+			}
+
+			return nil
+		})
+		fatal(err)
+	}
+
+	fatal(w.Flush())
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
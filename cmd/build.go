@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/agayev169/golox"
+)
+
+// runBuild compiles a script to a standalone Go program, or to a WebAssembly
+// module in the text format with -target wat:
+//
+//	golox build script.lox [-target go|wat] [-o out.go|out.wat]
+func runBuild(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	out := fs.String("o", "", "write the Go program, or the WebAssembly text module with -target wat, to `file` instead of stdout")
+	target := fs.String("target", "go", "compile to a Go program (go) or a WebAssembly text module (wat)")
+	_ = fs.Parse(args)
+
+	// Flags may follow the script too.
+	path := fs.Arg(0)
+	if fs.NArg() > 0 {
+		_ = fs.Parse(fs.Args()[1:])
+	}
+
+	if path == "" || fs.NArg() != 0 || (*target != "go" && *target != "wat") {
+		fmt.Fprintf(os.Stderr, "Usage: golox build script [-target go|wat] [-o file]\n")
+		fs.PrintDefaults()
+		os.Exit(64)
+	}
+
+	src, err := buildFile(path, *target)
+	fatal(err)
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+
+	fatal(err)
+}
+
+// buildFile returns the source of a program running the script at path,
+// compiled for target.
+func buildFile(path, target string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stmts, lerr := parse(f)
+	if lerr != nil {
+		return nil, lerr
+	}
+
+	interp := golox.NewInterpreter()
+	if lerr = golox.NewResolver(interp).Resolve(stmts); lerr != nil {
+		return nil, lerr
+	}
+
+	stmts = golox.NewOptimizer().Optimize(stmts)
+
+	if target == "wat" {
+		src, lerr := golox.NewWatCompiler(interp).Compile(stmts)
+		if lerr != nil {
+			return nil, lerr
+		}
+
+		return src, nil
+	}
+
+	return golox.NewGoCompiler(interp).Compile(stmts)
+}
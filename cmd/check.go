@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/agayev169/golox"
+)
+
+// runCheck type checks the given scripts without running them, printing every
+// error found, and exits with a non-zero code if there is any.
+func runCheck(paths []string) {
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: golox check script...")
+		os.Exit(64)
+	}
+
+	failed := false
+
+	for _, path := range paths {
+		errs, err := checkFile(path)
+		fatal(err)
+
+		for _, lerr := range errs {
+			fmt.Printf("%s:%d:%d: %s\n", path, lerr.Line, lerr.Col, lerr.Msg)
+		}
+
+		failed = failed || len(errs) > 0
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkFile returns the compile and type errors of the script at path.
+func checkFile(path string) ([]*golox.LoxError, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, lerr := parse(bytes.NewReader(src))
+	if lerr != nil {
+		return []*golox.LoxError{lerr}, nil
+	}
+
+	if lerr = golox.NewResolver(golox.NewInterpreter()).Resolve(stmts); lerr != nil {
+		return []*golox.LoxError{lerr}, nil
+	}
+
+	return golox.NewChecker().Check(stmts), nil
+}
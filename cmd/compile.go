@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/agayev169/golox"
+)
+
+// runCompile parses and resolves a script and saves the result, which running
+// the script loads instead of parsing it again while the script is unchanged.
+// A program saved with -o is loaded by `golox run -c file`:
+//
+//	golox compile script.lox [-o script.loxc]
+func runCompile(args []string) {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	out := fs.String("o", "", "write the program to `file` instead of the script's path followed by c")
+	_ = fs.Parse(args)
+
+	// Flags may follow the script too.
+	path := fs.Arg(0)
+	if fs.NArg() > 0 {
+		_ = fs.Parse(fs.Args()[1:])
+	}
+
+	if path == "" || fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: golox compile script [-o file]\n")
+		fs.PrintDefaults()
+		os.Exit(64)
+	}
+
+	if *out == "" {
+		*out = compiledPath(path)
+	}
+
+	fatal(compileFile(path, *out))
+}
+
+// compileFile saves the program of the script at path to out.
+func compileFile(path, out string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	stmts, lerr := parse(bytes.NewReader(src))
+	if lerr != nil {
+		return lerr
+	}
+
+	interp := golox.NewInterpreter()
+	if lerr = golox.NewResolver(interp).Resolve(stmts); lerr != nil {
+		return lerr
+	}
+
+	var buf bytes.Buffer
+	if err = golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		return err
+	}
+
+	return os.WriteFile(out, buf.Bytes(), 0o644)
+}
+
+// compiledPath returns where `golox compile` saves the program of the script
+// at path by default.
+func compiledPath(path string) string {
+	return path + "c"
+}
+
+// loadCompiled returns the statements saved at path by `golox compile` if they
+// were compiled from src, the current source of the script. They are resolved
+// with interp.
+func loadCompiled(path string, src []byte, interp *golox.Interpreter) ([]golox.Stmt, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	stmts, err := golox.ReadProgram(f, golox.SourceHash(src), interp)
+	if err != nil {
+		if !errors.Is(err, golox.ErrStaleProgram) {
+			fmt.Fprintf(os.Stderr, "Ignoring %s: %v\n", path, err)
+		}
+
+		return nil, false
+	}
+
+	return stmts, true
+}
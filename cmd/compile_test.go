@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+func TestCompileOutput(t *testing.T) {
+	dir, err := os.MkdirTemp("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.lox")
+	out := filepath.Join(dir, "out.loxc")
+
+	src := []byte("fun twice(x) { return 2 * x; }\nvar a = twice(21);\n")
+	if err = os.WriteFile(script, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = compileFile(script, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(compiledPath(script)); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing at %s, got %v", compiledPath(script), err)
+	}
+
+	if _, ok := loadCompiled(out, src, golox.NewInterpreter()); !ok {
+		t.Fatalf("Expected the program saved at %s to be loaded", out)
+	}
+}
+
+func TestRunCompiled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.lox")
+	out := filepath.Join(dir, "out.loxc")
+
+	src := []byte("var a = 1;\n")
+	if err = os.WriteFile(script, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Save another program under the hash of the script, so that the value of
+	// a tells which of the two ran.
+	stmts, lerr := parse(bytes.NewReader([]byte("var a = 2;\n")))
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	interp := golox.NewInterpreter()
+	if lerr = golox.NewResolver(interp).Resolve(stmts); lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var buf bytes.Buffer
+	if err = golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		compiled string
+		expected string
+	}{
+		{compiledPath(script), "1"},
+		{out, "2"},
+	} {
+		interp := golox.NewInterpreter()
+		if err := runFile(script, test.compiled, interp, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if a := golox.Stringify(interp.Globals()["a"]); a != test.expected {
+			t.Errorf("Expected a = %s when loading %s, got %s", test.expected, test.compiled, a)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// Key codes of the control characters understood by the editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads lines from a terminal in raw mode, supporting cursor
+// movement, history navigation and tab completion. When raw is false it
+// reads plain lines and leaves echoing and editing to the terminal.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	raw      bool
+	history  []string
+	complete func(prefix string) []string
+	// Switches the terminal to raw mode for the duration of ReadLine.
+	makeRaw func() (func(), error)
+
+	line []rune
+	pos  int
+}
+
+func newLineEditor(in io.Reader, out io.Writer, raw bool) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, raw: raw, history: make([]string, 0)}
+}
+
+// ReadLine reads a line without its trailing newline. It returns io.EOF on
+// Ctrl-D at an empty line and errInterrupted on Ctrl-C.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+
+	if !e.raw {
+		line, err := e.in.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	if e.makeRaw != nil {
+		restore, err := e.makeRaw()
+		if err != nil {
+			return "", err
+		}
+
+		defer restore()
+	}
+
+	e.line, e.pos = make([]rune, 0), 0
+	histIdx := len(e.history)
+	// The line being typed before browsing the history.
+	pending := ""
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, keyLineFeed:
+			fmt.Fprint(e.out, "\r\n")
+
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+
+				return "", io.EOF
+			}
+
+			e.deleteAt(e.pos)
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			e.moveCursor(-1)
+		case keyCtrlF:
+			e.moveCursor(1)
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = e.line[e.pos:]
+			e.pos = 0
+		case keyCtrlP, keyCtrlN:
+			histIdx, pending = e.browseHistory(histIdx, pending, r == keyCtrlP)
+		case keyTab:
+			e.completeWord()
+		case keyEscape:
+			switch e.readEscape() {
+			case 'A':
+				histIdx, pending = e.browseHistory(histIdx, pending, true)
+			case 'B':
+				histIdx, pending = e.browseHistory(histIdx, pending, false)
+			case 'C':
+				e.moveCursor(1)
+			case 'D':
+				e.moveCursor(-1)
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.line)
+			case '~':
+				e.deleteAt(e.pos)
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+
+		e.refresh(prompt)
+	}
+}
+
+// AddHistory appends a line to the history unless it repeats the last one.
+func (e *lineEditor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+}
+
+// readEscape consumes an ANSI escape sequence and returns its final byte.
+// The only sequence with parameters that is recognized is `ESC [ 3 ~` (delete).
+func (e *lineEditor) readEscape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+
+	params := strings.Builder{}
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+
+		if (r < '0' || r > '9') && r != ';' {
+			break
+		}
+
+		params.WriteRune(r)
+	}
+
+	if r == '~' && params.String() != "3" {
+		return 0
+	}
+
+	return r
+}
+
+func (e *lineEditor) browseHistory(idx int, pending string, back bool) (int, string) {
+	if idx == len(e.history) {
+		pending = string(e.line)
+	}
+
+	if back && idx > 0 {
+		idx--
+	} else if !back && idx < len(e.history) {
+		idx++
+	} else {
+		return idx, pending
+	}
+
+	if idx == len(e.history) {
+		e.line = []rune(pending)
+	} else {
+		e.line = []rune(e.history[idx])
+	}
+
+	e.pos = len(e.line)
+
+	return idx, pending
+}
+
+// completeWord completes the identifier before the cursor. A single candidate
+// is inserted, otherwise the longest common prefix is inserted or, if there
+// is none, the candidates are listed.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start := e.pos
+	for start > 0 && (unicode.IsLetter(e.line[start-1]) || unicode.IsDigit(e.line[start-1]) || e.line[start-1] == '_') {
+		start--
+	}
+
+	prefix := string(e.line[start:e.pos])
+	if prefix == "" {
+		return
+	}
+
+	candidates := e.complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		e.insert([]rune(common[len(prefix):]))
+
+		return
+	}
+
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func commonPrefix(words []string) string {
+	res := words[0]
+
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, res) {
+			_, size := utf8.DecodeLastRuneInString(res)
+			res = res[:len(res)-size]
+		}
+	}
+
+	return res
+}
+
+func (e *lineEditor) insert(rs []rune) {
+	line := make([]rune, 0, len(e.line)+len(rs))
+	line = append(line, e.line[:e.pos]...)
+	line = append(line, rs...)
+	e.line = append(line, e.line[e.pos:]...)
+	e.pos += len(rs)
+}
+
+func (e *lineEditor) deleteAt(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+func (e *lineEditor) moveCursor(delta int) {
+	if pos := e.pos + delta; pos >= 0 && pos <= len(e.line) {
+		e.pos = pos
+	}
+}
+
+// refresh redraws the prompt and the line and puts the cursor in place.
+func (e *lineEditor) refresh(prompt string) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(e.line))
+
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var editorTestData = map[string]struct {
+	Keys     string
+	History  []string
+	Expected []string
+}{
+	"insert and move": {
+		Keys:     "prnt 1;\x1b[D\x1b[D\x1b[D\x1b[D\x1b[Di\r",
+		Expected: []string{"print 1;"},
+	},
+	"home, end and kill": {
+		Keys:     "x = 1;\x01var \x05 junk\x02\x02\x02\x02\x02\x0b\r",
+		Expected: []string{"var x = 1;"},
+	},
+	"backspace and delete": {
+		Keys:     "abXc\x7f\x7f\x7fbc\x01\x1b[3~a\r",
+		Expected: []string{"abc"},
+	},
+	"history": {
+		Keys:     "\x1b[A\x1b[A\r\x1b[A\x1b[B\x1b[Bnew\r",
+		History:  []string{"first", "second"},
+		Expected: []string{"first", "new"},
+	},
+	"completion": {
+		Keys:     "print fi\t\to\t;\r",
+		Expected: []string{"print fibonacci;"},
+	},
+	"unicode": {
+		Keys:     "\"ñu\"\x1b[D\x1b[Dá\r",
+		Expected: []string{"\"ñáu\""},
+	},
+}
+
+func TestLineEditor(t *testing.T) {
+	for k, tv := range editorTestData {
+		e := newLineEditor(strings.NewReader(tv.Keys), ioutil.Discard, true)
+		e.complete = func(prefix string) []string {
+			res := make([]string, 0)
+			for _, name := range []string{"fibonacci", "fib2", "clock"} {
+				if strings.HasPrefix(name, prefix) {
+					res = append(res, name)
+				}
+			}
+
+			return res
+		}
+
+		for _, h := range tv.History {
+			e.AddHistory(h)
+		}
+
+		for _, expected := range tv.Expected {
+			line, err := e.ReadLine("> ")
+			if err != nil {
+				t.Fatalf("Failed on test %s. Got error on e.ReadLine(): %v", k, err)
+			}
+
+			e.AddHistory(line)
+
+			if line != expected {
+				t.Fatalf("Failed on test %s. Expected: %q, got: %q", k, expected, line)
+			}
+		}
+
+		if _, err := e.ReadLine("> "); err != io.EOF {
+			t.Fatalf("Failed on test %s. Expected io.EOF at the end of input, got %v", k, err)
+		}
+	}
+}
+
+func TestLineEditorCtrlD(t *testing.T) {
+	e := newLineEditor(strings.NewReader("ab\x01\x04\r\x04"), ioutil.Discard, true)
+
+	if line, err := e.ReadLine("> "); err != nil || line != "b" {
+		t.Fatalf("Expected Ctrl-D to delete a character, got %q, %v", line, err)
+	}
+
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Fatalf("Expected Ctrl-D on an empty line to return io.EOF, got %v", err)
+	}
+}
@@ -1,12 +1,10 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -18,91 +16,81 @@ func main() {
 	log.SetFormatter(&log.JSONFormatter{})
 
 	args := os.Args
-	if len(args) > 2 {
-		log.Printf("Usage: %s [script]\n", args[0])
+	if len(args) >= 2 && args[1] == "test" {
+		runTests(args[2:])
+	} else if len(args) >= 2 && args[1] == "bench" {
+		runBench(args[2:])
+	} else if len(args) >= 2 && args[1] == "run" {
+		runCommand(args[2:])
+	} else if len(args) >= 2 && args[1] == "check" {
+		runCheck(args[2:])
+	} else if len(args) >= 2 && args[1] == "build" {
+		runBuild(args[2:])
+	} else if len(args) >= 2 && args[1] == "compile" {
+		runCompile(args[2:])
+	} else if len(args) > 2 {
+		log.Printf("Usage: %s [script] | run [flags] script | check script... | build script [-target go|wat] [-o file] | compile script [-o file] | test [path...] | bench [-runs n] [path...]\n", args[0])
 		os.Exit(64)
 	} else if len(args) == 2 {
-		runFile(args[1])
+		fatal(runFile(args[1], compiledPath(args[1]), golox.NewInterpreter(), true))
 	} else if len(args) == 1 {
 		runPrompt()
 	}
 }
 
-func runFile(path string) {
-	f, err := os.Open(path)
-	fatal(err)
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(f)
-
-	r := bufio.NewReader(f)
-
-	interp := golox.NewInterpreter()
-
-	_, err = run(r, interp)
+// runFile runs the script at path, optimizing it first if optimize is set.
+// The program saved at compiled by `golox compile` is run instead of parsing
+// the script if it is up to date.
+func runFile(path, compiled string, interp *golox.Interpreter, optimize bool) error {
+	src, err := os.ReadFile(path)
 	if err != nil {
-		fatal(err)
+		return err
 	}
-}
 
-func runPrompt() {
-	reader := bufio.NewReader(os.Stdin)
+	// A program saved by `golox compile` is run without parsing the script.
+	if stmts, ok := loadCompiled(compiled, src, interp); ok {
+		_, err = interpret(stmts, interp, optimize)
 
-	interp := golox.NewInterpreter()
+		return err
+	}
 
-	for {
-		fmt.Print("> ")
-		line, err := reader.ReadString('\n')
-		fatal(err)
+	_, err = run(bytes.NewReader(src), interp, optimize)
 
-		res, err := run(bufio.NewReader(strings.NewReader(line)), interp)
-		if !warn(err) && res != nil {
-			if _, ok := res.(golox.Nil); ok {
-				fmt.Println("nil")
-			} else {
-				fmt.Println(res)
-			}
-		}
-	}
+	return err
 }
 
-func run(r *bufio.Reader, interp *golox.Interpreter) (interface{}, error) {
-	var lerr *golox.LoxError
-	bs, err := io.ReadAll(r)
-
-	if err != nil {
-		return nil, err
+func run(r io.Reader, interp *golox.Interpreter, optimize bool) (golox.Value, error) {
+	stmts, lerr := parse(r)
+	if lerr != nil {
+		return golox.NilValue, lerr
 	}
 
-	s := golox.NewScanner(bytes.NewReader(bs))
+	return execute(stmts, interp, optimize)
+}
 
-	tokens, err := s.ScanTokens()
+func parse(r io.Reader) ([]golox.Stmt, *golox.LoxError) {
+	return golox.NewStreamParser(golox.NewScanner(r)).Parse()
+}
 
-	if err != nil {
-		return nil, err
+func execute(stmts []golox.Stmt, interp *golox.Interpreter, optimize bool) (golox.Value, error) {
+	resolver := golox.NewResolver(interp)
+	if lerr := resolver.Resolve(stmts); lerr != nil {
+		return golox.NilValue, lerr
 	}
 
-	log.Info("Scanned the following tokens: ", tokens)
-
-	p := golox.NewParser(tokens)
+	return interpret(stmts, interp, optimize)
+}
 
-	stmts, lerr := p.Parse()
-	if lerr != nil {
-		return nil, lerr
-	}
-
-	resolver := golox.NewResolver(interp)
-	if lerr = resolver.Resolve(stmts); lerr != nil {
-		return nil, lerr
+// interpret runs stmts, which interp has resolved.
+func interpret(stmts []golox.Stmt, interp *golox.Interpreter, optimize bool) (golox.Value, error) {
+	if optimize {
+		stmts = golox.NewOptimizer().Optimize(stmts)
 	}
 
 	res, lerr := interp.Interpret(stmts)
 
 	if lerr != nil {
-		return nil, lerr
+		return golox.NilValue, lerr
 	}
 
 	return res, nil
@@ -113,7 +101,7 @@ func fatal(err error) {
 		return
 	}
 
-	fmt.Printf("Error happened: %s\n", err.Error())
+	printError(os.Stdout, err)
 
 	os.Exit(1)
 }
@@ -123,7 +111,18 @@ func warn(err error) bool {
 		return false
 	}
 
-	fmt.Printf("Error happened: %s\n", err.Error())
+	printError(os.Stdout, err)
 
 	return true
 }
+
+// printError prints err followed by its stack trace, if it has one.
+func printError(w io.Writer, err error) {
+	fmt.Fprintf(w, "Error happened: %s\n", err.Error())
+
+	if lerr, ok := err.(*golox.LoxError); ok {
+		for _, line := range lerr.Trace {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agayev169/golox"
+)
+
+const historyLimit = 1000
+
+type repl struct {
+	interp *golox.Interpreter
+	editor *lineEditor
+	out    io.Writer
+	// Where entered lines are appended to, nil if history is not persisted.
+	history io.WriteCloser
+	quit    bool
+}
+
+type replCommand struct {
+	name  string
+	usage string
+	run   func(r *repl, arg string)
+}
+
+var replCommands []replCommand
+
+func init() {
+	replCommands = []replCommand{
+		{"help", ":help                 show this help", (*repl).cmdHelp},
+		{"env", ":env                  list global bindings", (*repl).cmdEnv},
+		{"ast", ":ast <code>           print the syntax tree of code", (*repl).cmdAst},
+		{"tokens", ":tokens <code>        print the tokens of code", (*repl).cmdTokens},
+		{"load", ":load <file>          run a file in the current session", (*repl).cmdLoad},
+		{"reset", ":reset                forget all definitions", (*repl).cmdReset},
+		{"time", ":time <code>          run code and report how long it took", (*repl).cmdTime},
+		{"quit", ":quit                 exit the REPL", (*repl).cmdQuit},
+	}
+}
+
+func runPrompt() {
+	raw := isTerminal(os.Stdin.Fd())
+
+	editor := newLineEditor(os.Stdin, os.Stdout, raw)
+	if raw {
+		editor.makeRaw = func() (func(), error) {
+			return makeRaw(os.Stdin.Fd())
+		}
+	}
+
+	r := newRepl(editor, os.Stdout)
+	r.openHistory(historyPath())
+	defer r.closeHistory()
+
+	r.run()
+}
+
+func newRepl(editor *lineEditor, out io.Writer) *repl {
+	r := &repl{editor: editor, out: out}
+	r.resetInterpreter()
+
+	editor.complete = r.completeGlobal
+
+	return r
+}
+
+func historyPath() string {
+	if p := os.Getenv("GOLOX_HISTORY"); p != "" {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".golox_history")
+}
+
+func (r *repl) run() {
+	// Input of a statement spanning several lines is accumulated in src
+	// until it parses.
+	src := strings.Builder{}
+
+	for !r.quit {
+		prompt := "> "
+		if src.Len() > 0 {
+			prompt = "... "
+		}
+
+		line, err := r.editor.ReadLine(prompt)
+		if err == errInterrupted {
+			src.Reset()
+			continue
+		}
+
+		eof := err == io.EOF
+		if err != nil && !eof {
+			fatal(err)
+		}
+
+		if eof && !r.editor.raw {
+			fmt.Fprintln(r.out)
+		}
+
+		if eof && strings.TrimSpace(src.String()) == "" {
+			// Ctrl-D on an empty prompt.
+			return
+		}
+
+		r.addHistory(line)
+
+		if src.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			r.runCommand(strings.TrimSpace(line))
+			continue
+		}
+
+		src.WriteString(line)
+		src.WriteString("\n")
+
+		stmts, lerr := parse(strings.NewReader(src.String()))
+		if lerr != nil && lerr.Incomplete() {
+			if bare := parseBareExpression(src.String()); bare != nil {
+				stmts, lerr = bare, nil
+			} else if !eof {
+				continue
+			}
+		}
+
+		src.Reset()
+
+		if lerr != nil {
+			r.warn(lerr)
+		} else {
+			r.execute(stmts)
+		}
+
+		if eof {
+			return
+		}
+	}
+}
+
+// execute runs stmts, the interpreter itself echoes the values of expression
+// statements.
+func (r *repl) execute(stmts []golox.Stmt) {
+	if _, err := execute(stmts, r.interp, true); err != nil {
+		r.warn(err)
+	}
+}
+
+// parseBareExpression parses src ending with an expression lacking its
+// semicolon, e.g. `1 + 2`. It returns nil if src is anything else.
+func parseBareExpression(src string) []golox.Stmt {
+	stmts, lerr := parse(strings.NewReader(src + ";"))
+	if lerr != nil || len(stmts) == 0 {
+		return nil
+	}
+
+	if _, ok := stmts[len(stmts)-1].(*golox.Expression); !ok {
+		return nil
+	}
+
+	return stmts
+}
+
+func (r *repl) warn(err error) {
+	printError(r.out, err)
+}
+
+func (r *repl) resetInterpreter() {
+	r.interp = golox.NewInterpreter()
+	r.interp.SetOutput(r.out)
+	r.interp.SetREPLMode(true)
+}
+
+func (r *repl) completeGlobal(prefix string) []string {
+	res := make([]string, 0)
+
+	for name := range r.interp.Globals() {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+// History
+
+func (r *repl) openHistory(path string) {
+	if path == "" {
+		return
+	}
+
+	if f, err := os.Open(path); err == nil {
+		lines := make([]string, 0)
+
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+
+		f.Close()
+
+		// Drop the oldest lines from the file too so that it stops growing.
+		if len(lines) > historyLimit {
+			lines = lines[len(lines)-historyLimit:]
+
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+				r.warn(err)
+			}
+		}
+
+		for _, line := range lines {
+			r.editor.AddHistory(line)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		r.warn(err)
+		return
+	}
+
+	r.history = f
+}
+
+func (r *repl) addHistory(line string) {
+	n := len(r.editor.history)
+	r.editor.AddHistory(line)
+
+	if r.history != nil && len(r.editor.history) > n {
+		fmt.Fprintln(r.history, line)
+	}
+}
+
+func (r *repl) closeHistory() {
+	if r.history != nil {
+		r.history.Close()
+	}
+}
+
+// Meta-commands
+
+func (r *repl) runCommand(line string) {
+	name, arg := line[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	for _, c := range replCommands {
+		if c.name == name {
+			c.run(r, arg)
+			return
+		}
+	}
+
+	fmt.Fprintf(r.out, "Unknown command ':%s'. Type :help for the list of commands.\n", name)
+}
+
+func (r *repl) cmdHelp(string) {
+	for _, c := range replCommands {
+		fmt.Fprintln(r.out, c.usage)
+	}
+}
+
+func (r *repl) cmdEnv(string) {
+	globals := r.interp.Globals()
+
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(r.out, "%s = %s\n", name, golox.Stringify(globals[name]))
+	}
+}
+
+func (r *repl) cmdAst(arg string) {
+	stmts, lerr := parse(strings.NewReader(arg))
+	if lerr != nil {
+		// Allow bare expressions without the trailing semicolon.
+		if stmts = parseBareExpression(arg); stmts == nil {
+			r.warn(lerr)
+			return
+		}
+
+		if e, ok := stmts[0].(*golox.Expression); ok && len(stmts) == 1 {
+			fmt.Fprintln(r.out, (&golox.AstPrinter{}).Print(e.Expr))
+			return
+		}
+	}
+
+	for _, stmt := range stmts {
+		fmt.Fprintln(r.out, (&golox.AstPrinter{}).PrintStmt(stmt))
+	}
+}
+
+func (r *repl) cmdTokens(arg string) {
+	tokens, err := golox.NewScanner(strings.NewReader(arg)).ScanTokens()
+	if err != nil {
+		r.warn(err)
+		return
+	}
+
+	for _, t := range tokens {
+		if t.Type == golox.STRING || t.Type == golox.NUMBER || t.Type == golox.INTERPOLATION {
+			fmt.Fprintf(r.out, "%d:%d\t%-12s %s\t%v\n", t.Line, t.Col, t.Type, t.Lexeme, t.Literal)
+		} else {
+			fmt.Fprintf(r.out, "%d:%d\t%-12s %s\n", t.Line, t.Col, t.Type, t.Lexeme)
+		}
+	}
+}
+
+func (r *repl) cmdLoad(arg string) {
+	f, err := os.Open(arg)
+	if err != nil {
+		r.warn(err)
+		return
+	}
+	defer f.Close()
+
+	stmts, lerr := parse(bufio.NewReader(f))
+	if lerr != nil {
+		r.warn(lerr)
+		return
+	}
+
+	if _, err = execute(stmts, r.interp, true); err != nil {
+		r.warn(err)
+	}
+}
+
+func (r *repl) cmdReset(string) {
+	r.resetInterpreter()
+}
+
+func (r *repl) cmdTime(arg string) {
+	stmts, lerr := parse(strings.NewReader(arg))
+	if lerr != nil {
+		// Allow bare expressions without the trailing semicolon.
+		if stmts = parseBareExpression(arg); stmts == nil {
+			r.warn(lerr)
+			return
+		}
+	}
+
+	start := time.Now()
+	r.execute(stmts)
+	fmt.Fprintf(r.out, "Elapsed: %s\n", time.Since(start))
+}
+
+func (r *repl) cmdQuit(string) {
+	r.quit = true
+}
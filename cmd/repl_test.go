@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runScript(t *testing.T, input string) string {
+	out := &bytes.Buffer{}
+	r := newRepl(newLineEditor(strings.NewReader(input), out, false), out)
+	r.run()
+
+	return strings.ReplaceAll(strings.ReplaceAll(out.String(), "... ", ""), "> ", "")
+}
+
+func TestReplMultiline(t *testing.T) {
+	out := runScript(t, "fun add(a,\n  b) {\n  return a + b;\n}\nprint add(1,\n2);\n")
+
+	if out != "3\n\n" {
+		t.Fatalf("Unexpected output: %q", out)
+	}
+}
+
+func TestReplCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "lib.lox")
+	if err = ioutil.WriteFile(script, []byte("fun twice(x) { return 2 * x; }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runScript(t, strings.Join([]string{
+		":load " + script,
+		"var a = twice(2);",
+		":env",
+		":ast a + 1",
+		":tokens a;",
+		":reset",
+		":env",
+		":quit",
+		"print \"unreachable\";",
+	}, "\n"))
+
+	expected := strings.Join([]string{
+		"a = 4",
+		"clock = <native fn>",
+		"len = <native fn>",
+		"twice = <fn twice>",
+		"(+ a 1)",
+		"1:1\tidentifier   a",
+		"1:2\t;            ;",
+		"1:3\tEOF          ",
+		"clock = <native fn>",
+		"len = <native fn>",
+		"",
+	}, "\n")
+
+	if out != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestReplHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history")
+
+	for _, input := range []string{"var a = 1;\n", "print a;\n"} {
+		r := newRepl(newLineEditor(strings.NewReader(input), ioutil.Discard, false), ioutil.Discard)
+		r.openHistory(path)
+		r.run()
+		r.closeHistory()
+	}
+
+	r := newRepl(newLineEditor(strings.NewReader(""), ioutil.Discard, false), ioutil.Discard)
+	r.openHistory(path)
+	defer r.closeHistory()
+
+	if h := strings.Join(r.editor.history, "|"); h != "var a = 1;|print a;" {
+		t.Fatalf("Unexpected history: %s", h)
+	}
+}
+
+func TestReplHistoryLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history")
+
+	lines := make([]string, 0)
+	for i := 0; i < historyLimit+10; i++ {
+		lines = append(lines, fmt.Sprintf("print %d;", i))
+	}
+
+	if err = ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRepl(newLineEditor(strings.NewReader("print 0;\n"), ioutil.Discard, false), ioutil.Discard)
+	r.openHistory(path)
+	r.run()
+	r.closeHistory()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join(append(lines[10:], "print 0;"), "\n") + "\n"
+	if string(data) != expected {
+		t.Fatalf("Expected the last %d lines and the new one in the history file, got %d lines", historyLimit, strings.Count(string(data), "\n"))
+	}
+}
+
+func TestReplEcho(t *testing.T) {
+	out := runScript(t, strings.Join([]string{
+		"1 + 2;",
+		"\"no\" + \" semicolon\"",
+		"var a = 1;",
+		"a = 5;",
+		"print a;",
+		"{ 10; }",
+		"fun f() { 20; }",
+		"f();",
+		"nil",
+	}, "\n"))
+
+	expected := "3\nno semicolon\n5\n5\nnil\nnil\n\n"
+	if out != expected {
+		t.Fatalf("Expected:\n%q\ngot:\n%q", expected, out)
+	}
+}
+
+func TestReplTime(t *testing.T) {
+	out := runScript(t, ":time 1 + 2\n")
+
+	if !strings.HasPrefix(out, "3\nElapsed: ") {
+		t.Fatalf("Unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/agayev169/golox"
+)
+
+// runCommand runs a script with optional instrumentation:
+//
+//	golox run [-c file] [-profile] [-pprof file] [-coverage file] [-coverage-html file] script
+func runCommand(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	compiled := fs.String("c", "", "load the program saved by golox compile from `file` instead of the script's path followed by c")
+	profile := fs.Bool("profile", false, "print the hottest functions and lines to stderr")
+	pprofPath := fs.String("pprof", "", "write a pprof profile to `file`")
+	coverage := fs.String("coverage", "", "write line, function and branch coverage in the lcov format to `file`")
+	coverageHTML := fs.String("coverage-html", "", "write the source annotated with coverage as HTML to `file`")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: golox run [flags] script\n")
+		fs.PrintDefaults()
+		os.Exit(64)
+	}
+
+	path := fs.Arg(0)
+	if *compiled == "" {
+		*compiled = compiledPath(path)
+	}
+
+	interp := golox.NewInterpreter()
+
+	var profiler *golox.Profiler
+	if *profile || *pprofPath != "" {
+		profiler = golox.NewProfiler(path)
+		interp.SetProfiler(profiler)
+	}
+
+	// Coverage runs are not optimized so that dead code is reported.
+	optimize := true
+
+	var cov *golox.Coverage
+	if *coverage != "" || *coverageHTML != "" {
+		cov = golox.NewCoverage(path)
+		interp.SetCoverage(cov)
+		optimize = false
+	}
+
+	err := runFile(path, *compiled, interp, optimize)
+
+	if *profile {
+		fmt.Fprintln(os.Stderr)
+		warn(profiler.WriteReport(os.Stderr, 20))
+	}
+
+	if *pprofPath != "" {
+		warn(writeFile(*pprofPath, profiler.WritePprof))
+	}
+
+	if cov != nil {
+		fmt.Fprintf(os.Stderr, "coverage: %s\n", cov.Summary())
+	}
+
+	if *coverage != "" {
+		warn(writeFile(*coverage, cov.WriteLcov))
+	}
+
+	if *coverageHTML != "" {
+		warn(writeCoverageHTML(cov, path, *coverageHTML))
+	}
+
+	fatal(err)
+}
+
+func writeCoverageHTML(c *golox.Coverage, src, path string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, func(w io.Writer) error {
+		return c.WriteHTML(w, data)
+	})
+}
+
+// writeFile creates the file at path and fills it with write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = write(f); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
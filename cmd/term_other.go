@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+// Line editing is only supported on Linux terminals, elsewhere the REPL
+// falls back to reading plain lines.
+func makeRaw(uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func isTerminal(uintptr) bool {
+	return false
+}
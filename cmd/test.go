@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agayev169/golox"
+)
+
+// runTests runs every .lox file found under paths against its expectation
+// annotations and exits with a non-zero code if any of them fails.
+func runTests(paths []string) {
+	if len(paths) == 0 {
+		paths = []string{"testdata"}
+	}
+
+	passed, failed := 0, 0
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(path, ".lox") {
+				return err
+			}
+
+			res, err := golox.RunTestFile(path)
+			if err != nil {
+				return err
+			}
+
+			if res.Passed() {
+				passed++
+				fmt.Printf("PASS %s\n", path)
+
+				return nil
+			}
+
+			failed++
+			fmt.Printf("FAIL %s\n", path)
+			for _, f := range res.Failures {
+				fmt.Printf("     %s\n", f)
+			}
+
+			return nil
+		})
+		fatal(err)
+	}
+
+	fmt.Printf("\n%d passed, %d failed.\n", passed, failed)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,211 @@
+package golox
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The conformance runner understands the annotations used by the test suite
+// of the Crafting Interpreters book:
+//
+//	print 1;    // expect: 1
+//	print a;    // expect runtime error: Undefined variable a
+//	var a = ;   // Error at ';': Expect expression.
+//	            // [line 7] Error at end: Expect '}' after block.
+//
+// A file passes when its stdout matches the `expect` lines in order and the
+// reported error, if any, matches the error annotation in line and message.
+
+var (
+	expectOutputRe       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeErrorRe = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectCompileErrorRe = regexp.MustCompile(`// (?:\[line (\d+)\] )?(Error[^:]*: (.+))`)
+)
+
+type ExpectedError struct {
+	Line int
+	Msg  string
+}
+
+func (e ExpectedError) String() string {
+	return fmt.Sprintf("[line %d] Error: %s", e.Line, e.Msg)
+}
+
+type TestExpectations struct {
+	Output        []string
+	RuntimeError  *ExpectedError
+	CompileErrors []ExpectedError
+}
+
+type TestResult struct {
+	Path     string
+	Failures []string
+}
+
+func (r *TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func (r *TestResult) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+func ParseExpectations(src []byte) *TestExpectations {
+	res := &TestExpectations{Output: make([]string, 0), CompileErrors: make([]ExpectedError, 0)}
+
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+
+		if m := expectOutputRe.FindStringSubmatch(text); m != nil {
+			res.Output = append(res.Output, m[1])
+		} else if m := expectRuntimeErrorRe.FindStringSubmatch(text); m != nil {
+			res.RuntimeError = &ExpectedError{Line: line, Msg: m[1]}
+		} else if m := expectCompileErrorRe.FindStringSubmatch(text); m != nil {
+			errLine := line
+			if m[1] != "" {
+				errLine, _ = strconv.Atoi(m[1])
+			}
+
+			res.CompileErrors = append(res.CompileErrors, ExpectedError{Line: errLine, Msg: m[3]})
+		}
+	}
+
+	return res
+}
+
+// RunTestFile runs the Lox script at path and checks it against its annotations.
+func RunTestFile(path string) (*TestResult, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunTestSource(path, src), nil
+}
+
+func RunTestSource(path string, src []byte) *TestResult {
+	return RunTestSourceWith(path, src, runTestScript)
+}
+
+// TestScript runs a script writing its output to out, and returns the error
+// it failed to compile or to run with.
+type TestScript func(src []byte, out *bytes.Buffer) (compileErr, runtimeErr *LoxError)
+
+// RunTestSourceWith checks a script run by run against its annotations, e.g.
+// to test another implementation of the language.
+func RunTestSourceWith(path string, src []byte, run TestScript) (res *TestResult) {
+	res = &TestResult{Path: path, Failures: make([]string, 0)}
+	exp := ParseExpectations(src)
+
+	out := &bytes.Buffer{}
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.fail("Interpreter panicked: %v", r)
+		}
+	}()
+
+	compileErr, runtimeErr := run(src, out)
+
+	checkOutput(res, exp.Output, out.String())
+	checkCompileErrors(res, exp.CompileErrors, compileErr)
+	checkRuntimeError(res, exp.RuntimeError, runtimeErr)
+
+	return res
+}
+
+func runTestScript(src []byte, out *bytes.Buffer) (compileErr, runtimeErr *LoxError) {
+	stmts, lerr := NewStreamParser(NewScanner(bytes.NewReader(src))).Parse()
+	if lerr != nil {
+		return lerr, nil
+	}
+
+	interp := NewInterpreter()
+	interp.SetOutput(out)
+
+	if lerr = NewResolver(interp).Resolve(stmts); lerr != nil {
+		return lerr, nil
+	}
+
+	_, lerr = interp.Interpret(NewOptimizer().Optimize(stmts))
+
+	return nil, lerr
+}
+
+func checkOutput(res *TestResult, expected []string, actual string) {
+	lines := strings.Split(actual, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	for i, line := range lines {
+		if i >= len(expected) {
+			res.fail("Got output '%s' when none was expected.", line)
+			continue
+		}
+
+		if line != expected[i] {
+			res.fail("Expected output '%s' on line %d and got '%s'.", expected[i], i+1, line)
+		}
+	}
+
+	for i := len(lines); i < len(expected); i++ {
+		res.fail("Missing expected output '%s'.", expected[i])
+	}
+}
+
+// checkCompileErrors compares actual with the expected compile errors. The
+// parser stops at the first error, so a script expecting several can never
+// pass and is reported as such.
+func checkCompileErrors(res *TestResult, expected []ExpectedError, actual *LoxError) {
+	if len(expected) > 1 {
+		res.fail("Expected %d compile errors, but only the first error of a script is reported.", len(expected))
+		return
+	}
+
+	if actual == nil {
+		for _, e := range expected {
+			res.fail("Missing expected error: %s", e)
+		}
+
+		return
+	}
+
+	got := ExpectedError{Line: actual.Line, Msg: strings.TrimSpace(actual.Msg)}
+
+	if len(expected) == 0 {
+		res.fail("Unexpected error: %s", got)
+	} else if expected[0] != got {
+		res.fail("Missing expected error: %s", expected[0])
+		res.fail("Unexpected error: %s", got)
+	}
+}
+
+func checkRuntimeError(res *TestResult, expected *ExpectedError, actual *LoxError) {
+	if expected == nil {
+		if actual != nil {
+			res.fail("Unexpected runtime error: [line %d] %s", actual.Line, actual.Msg)
+		}
+
+		return
+	}
+
+	if actual == nil {
+		res.fail("Expected runtime error '%s' and got none.", expected.Msg)
+		return
+	}
+
+	if msg := strings.TrimSpace(actual.Msg); msg != expected.Msg {
+		res.fail("Expected runtime error '%s' and got '%s'.", expected.Msg, msg)
+	}
+
+	if actual.Line != expected.Line {
+		res.fail("Expected runtime error on line %d but was on line %d.", expected.Line, actual.Line)
+	}
+}
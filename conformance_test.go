@@ -0,0 +1,30 @@
+package golox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+func TestConformance(t *testing.T) {
+	forEachConformanceFile(t, func(t *testing.T, path string, src []byte) {
+		res, err := golox.RunTestFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, f := range res.Failures {
+			t.Error(f)
+		}
+	})
+}
+
+func TestConformanceSeveralCompileErrors(t *testing.T) {
+	src := []byte("var = 1; // Error at '=': Expected variable name.\nvar = 2; // Error at '=': Expected variable name.\n")
+
+	res := golox.RunTestSource("several.lox", src)
+	if len(res.Failures) != 1 || !strings.Contains(res.Failures[0], "only the first error") {
+		t.Errorf("Expected a single failure about several errors, got %v", res.Failures)
+	}
+}
@@ -4,16 +4,29 @@ type ControlType int
 
 const (
 	Ret ControlType = iota
+	// A return whose value is a call to Fn with Args. The call is made by the
+	// caller of the returning function, see LoxFunction.call.
+	TailCall
 )
 
 type Control struct {
 	Type ControlType
-	Val  interface{}
+	Val  Value
+	Fn   *LoxFunction
+	Args []Value
 }
 
-func NewReturn(val interface{}) *Control {
+func NewReturn(val Value) *Control {
 	return &Control{
 		Type: Ret,
 		Val:  val,
 	}
 }
+
+func NewTailCall(fn *LoxFunction, args []Value) *Control {
+	return &Control{
+		Type: TailCall,
+		Fn:   fn,
+		Args: args,
+	}
+}
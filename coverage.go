@@ -0,0 +1,305 @@
+package golox
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Coverage records which statements, functions and branches of a script the
+// Interpreter executed. Every statement interpreted while a Coverage is set
+// is registered first, so the code that never runs is reported too.
+type Coverage struct {
+	file     string
+	stmts    map[Stmt]*stmtCount
+	funcs    map[*Func]*FuncCoverage
+	branches map[interface{}]*BranchCoverage
+	// The branch points in the order they were registered.
+	branchNodes []interface{}
+}
+
+type stmtCount struct {
+	line int
+	hits int
+}
+
+type FuncCoverage struct {
+	Name string
+	Line int
+	Hits int
+}
+
+// BranchCoverage counts how many times each way of a branch point was taken:
+// the then and else branches of an if or a conditional expression, entering
+// and leaving a while body, and whether a logical operator evaluated its right
+// operand.
+type BranchCoverage struct {
+	Line  int
+	Taken [2]int
+}
+
+// LineCoverage is the number of times the statements starting on a line were
+// executed.
+type LineCoverage struct {
+	Line int
+	Hits int
+}
+
+func NewCoverage(file string) *Coverage {
+	return &Coverage{
+		file:     file,
+		stmts:    make(map[Stmt]*stmtCount),
+		funcs:    make(map[*Func]*FuncCoverage),
+		branches: make(map[interface{}]*BranchCoverage),
+	}
+}
+
+// SetCoverage makes the interpreter record coverage in c. A nil c disables it.
+func (interp *Interpreter) SetCoverage(c *Coverage) {
+	interp.coverage = c
+}
+
+// Lines returns the coverage of every line holding a statement, in order.
+func (c *Coverage) Lines() []LineCoverage {
+	hits := make(map[int]int)
+	for _, s := range c.stmts {
+		if s.line == 0 {
+			continue
+		}
+
+		if h, ok := hits[s.line]; !ok || s.hits > h {
+			hits[s.line] = s.hits
+		}
+	}
+
+	res := make([]LineCoverage, 0, len(hits))
+	for line, h := range hits {
+		res = append(res, LineCoverage{Line: line, Hits: h})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// Functions returns the coverage of every function, in source order.
+func (c *Coverage) Functions() []FuncCoverage {
+	res := make([]FuncCoverage, 0, len(c.funcs))
+	for _, f := range c.funcs {
+		res = append(res, *f)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// Branches returns the coverage of every branch point, in source order.
+func (c *Coverage) Branches() []BranchCoverage {
+	res := make([]BranchCoverage, 0, len(c.branchNodes))
+	for _, node := range c.branchNodes {
+		res = append(res, *c.branches[node])
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// WriteLcov writes the coverage in the lcov tracefile format.
+func (c *Coverage) WriteLcov(w io.Writer) error {
+	fmt.Fprintf(w, "TN:\nSF:%s\n", c.file)
+
+	funcs := c.Functions()
+	hitFuncs := 0
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FN:%d,%s\n", f.Line, f.Name)
+	}
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FNDA:%d,%s\n", f.Hits, f.Name)
+		if f.Hits > 0 {
+			hitFuncs++
+		}
+	}
+	fmt.Fprintf(w, "FNF:%d\nFNH:%d\n", len(funcs), hitFuncs)
+
+	branches := c.Branches()
+	hitBranches := 0
+	for i, b := range branches {
+		for j, taken := range b.Taken {
+			fmt.Fprintf(w, "BRDA:%d,%d,%d,%d\n", b.Line, i, j, taken)
+			if taken > 0 {
+				hitBranches++
+			}
+		}
+	}
+	fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", 2*len(branches), hitBranches)
+
+	lines := c.Lines()
+	hitLines := 0
+	for _, l := range lines {
+		fmt.Fprintf(w, "DA:%d,%d\n", l.Line, l.Hits)
+		if l.Hits > 0 {
+			hitLines++
+		}
+	}
+	fmt.Fprintf(w, "LF:%d\nLH:%d\n", len(lines), hitLines)
+
+	_, err := fmt.Fprintln(w, "end_of_record")
+
+	return err
+}
+
+// Summary describes the share of lines and branches that were executed.
+func (c *Coverage) Summary() string {
+	lines, hitLines := c.Lines(), 0
+	for _, l := range lines {
+		if l.Hits > 0 {
+			hitLines++
+		}
+	}
+
+	branches, hitBranches := c.Branches(), 0
+	for _, b := range branches {
+		for _, taken := range b.Taken {
+			if taken > 0 {
+				hitBranches++
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s: %s of lines, %s of branches",
+		c.file, percent(hitLines, len(lines)), percent(hitBranches, 2*len(branches)))
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+func (c *Coverage) hitStmt(s Stmt) {
+	if sc, ok := c.stmts[s]; ok {
+		sc.hits++
+	}
+}
+
+func (c *Coverage) hitFunc(f *Func) {
+	if fc, ok := c.funcs[f]; ok {
+		fc.Hits++
+	}
+}
+
+// branch records that the branch point node took the way i.
+func (c *Coverage) branch(node interface{}, i int) {
+	if b, ok := c.branches[node]; ok {
+		b.Taken[i]++
+	}
+}
+
+// register adds the statements, functions and branch points of stmts.
+func (c *Coverage) register(stmts []Stmt) {
+	for _, s := range stmts {
+		c.registerStmt(s)
+	}
+}
+
+func (c *Coverage) registerStmt(s Stmt) {
+	if s == nil {
+		return
+	}
+
+	if _, ok := s.(*Block); !ok {
+		if _, ok = c.stmts[s]; ok {
+			return
+		}
+
+		c.stmts[s] = &stmtCount{line: stmtLine(s)}
+	}
+
+	switch st := s.(type) {
+	case *Block:
+		c.register(st.Stmts)
+	case *Expression:
+		c.registerExpr(st.Expr)
+	case *Print:
+		c.registerExpr(st.Expr)
+	case *Var:
+		c.registerExpr(st.Initializer)
+	case *Func:
+		c.registerFunc(st)
+	case *If:
+		c.addBranch(st, st.Keyword.Line)
+		c.registerExpr(st.Condition)
+		c.registerStmt(st.Body)
+		c.registerStmt(st.ElseBody)
+	case *While:
+		c.addBranch(st, st.Keyword.Line)
+		c.registerExpr(st.Condition)
+		c.registerStmt(st.Body)
+	case *Return:
+		c.registerExpr(st.Value)
+	case *Throw:
+		c.registerExpr(st.Value)
+	case *Try:
+		c.register(st.Body)
+		c.register(st.CatchBody)
+		c.register(st.FinallyBody)
+	}
+}
+
+func (c *Coverage) registerExpr(e Expr) {
+	switch ex := e.(type) {
+	case *Assign:
+		c.registerExpr(ex.Value)
+	case *Binary:
+		c.registerExpr(ex.Left)
+		c.registerExpr(ex.Right)
+	case *Grouping:
+		c.registerExpr(ex.Expr)
+	case *Unary:
+		c.registerExpr(ex.Right)
+	case *Call:
+		c.registerExpr(ex.Callee)
+		for _, arg := range ex.Args {
+			c.registerExpr(arg)
+		}
+	case *Conditional:
+		c.addBranch(ex, ex.Question.Line)
+		c.registerExpr(ex.Condition)
+		c.registerExpr(ex.Then)
+		c.registerExpr(ex.Else)
+	case *CompoundAssign:
+		c.registerExpr(ex.Value)
+	case *Get:
+		c.registerExpr(ex.Object)
+	case *Lambda:
+		c.registerFunc(ex.Decl)
+	case *Logical:
+		c.addBranch(ex, ex.Operator.Line)
+		c.registerExpr(ex.Left)
+		c.registerExpr(ex.Right)
+	}
+}
+
+func (c *Coverage) registerFunc(f *Func) {
+	c.funcs[f] = &FuncCoverage{Name: f.Name.Lexeme, Line: f.Name.Line}
+	c.register(f.Body)
+}
+
+func (c *Coverage) addBranch(node interface{}, line int) {
+	if _, ok := c.branches[node]; ok {
+		return
+	}
+
+	c.branches[node] = &BranchCoverage{Line: line}
+	c.branchNodes = append(c.branchNodes, node)
+}
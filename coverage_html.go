@@ -0,0 +1,85 @@
+package golox
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.File}} coverage</title>
+<style>
+body { background: #fff; color: #222; font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+.num, .hits { color: #888; text-align: right; }
+.hit { background: #dfd; }
+.partial { background: #ffd; }
+.miss { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>{{.File}}</h1>
+<p>{{.Summary}}</p>
+<table>
+{{range .Lines}}<tr class="{{.Class}}"><td class="num">{{.Num}}</td><td class="hits">{{.Hits}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type htmlLine struct {
+	Num   int
+	Hits  string
+	Class string
+	Text  string
+}
+
+// WriteHTML renders src, the source of the covered script, with executed
+// lines in green, lines with a branch never taken in yellow and lines never
+// executed in red.
+func (c *Coverage) WriteHTML(w io.Writer, src []byte) error {
+	hits := make(map[int]int)
+	for _, l := range c.Lines() {
+		hits[l.Line] = l.Hits
+	}
+
+	partial := make(map[int]bool)
+	for _, b := range c.Branches() {
+		if b.Taken[0] == 0 || b.Taken[1] == 0 {
+			partial[b.Line] = true
+		}
+	}
+
+	text := strings.TrimSuffix(string(src), "\n")
+	lines := make([]htmlLine, 0)
+
+	for i, t := range strings.Split(text, "\n") {
+		l := htmlLine{Num: i + 1, Text: strings.TrimSuffix(t, "\r")}
+
+		if h, ok := hits[l.Num]; ok {
+			switch {
+			case h == 0:
+				l.Class = "miss"
+			case partial[l.Num]:
+				l.Class = "partial"
+			default:
+				l.Class = "hit"
+			}
+
+			l.Hits = strconv.Itoa(h)
+		}
+
+		lines = append(lines, l)
+	}
+
+	return coverageTemplate.Execute(w, struct {
+		File    string
+		Summary string
+		Lines   []htmlLine
+	}{c.file, c.Summary(), lines})
+}
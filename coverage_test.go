@@ -0,0 +1,82 @@
+package golox_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+const coveredSource = `fun abs(n) {
+  if (n < 0) return -n;
+  return n;
+}
+
+fun unused() {
+  print "never";
+}
+
+var i = 0;
+while (i < 3) {
+  print abs(i);
+  i = i + 1;
+}
+
+print true or unused();
+`
+
+func TestCoverage(t *testing.T) {
+	stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(coveredSource))).Parse()
+	if err != nil {
+		t.Fatalf("Got error on p.Parse(): %v", err)
+	}
+
+	interp := golox.NewInterpreter()
+	interp.SetOutput(io.Discard)
+
+	c := golox.NewCoverage("abs.lox")
+	interp.SetCoverage(c)
+
+	if err = golox.NewResolver(interp).Resolve(stmts); err != nil {
+		t.Fatalf("Got error on r.Resolve(): %v", err)
+	}
+
+	if _, err = interp.Interpret(stmts); err != nil {
+		t.Fatalf("Got error on interp.Interpret(): %v", err)
+	}
+
+	lcov := &bytes.Buffer{}
+	if err := c.WriteLcov(lcov); err != nil {
+		t.Fatalf("Got error on c.WriteLcov(): %v", err)
+	}
+
+	expected := []string{
+		"SF:abs.lox",
+		"FNDA:3,abs", "FNDA:0,unused",
+		// The if on line 2 never takes its then branch.
+		"BRDA:2,0,0,0", "BRDA:2,0,1,3",
+		// The while body runs three times and the loop exits once.
+		"BRDA:11,1,0,3", "BRDA:11,1,1,1",
+		// The or short-circuits and never evaluates its right operand.
+		"BRDA:16,2,0,1", "BRDA:16,2,1,0",
+		"DA:2,3", "DA:7,0", "DA:12,3",
+		"LF:10", "LH:9",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(lcov.String(), line+"\n") {
+			t.Fatalf("Expected %q in the lcov report:\n%s", line, lcov.String())
+		}
+	}
+
+	html := &bytes.Buffer{}
+	if err := c.WriteHTML(html, []byte(coveredSource)); err != nil {
+		t.Fatalf("Got error on c.WriteHTML(): %v", err)
+	}
+
+	if !strings.Contains(html.String(), `<tr class="miss"><td class="num">7</td>`) {
+		t.Fatalf("Expected line 7 to be marked as missed:\n%s", html.String())
+	}
+}
@@ -0,0 +1,51 @@
+package golox_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+const stackTraceSource = `
+fun fail(x) {
+  return x + nil;
+}
+fun middle() {
+  var r = fail(1);
+  return r;
+}
+fun outer() {
+  return middle();
+}
+outer();
+`
+
+func TestStackTrace(t *testing.T) {
+	stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(stackTraceSource))).Parse()
+	if err != nil {
+		t.Fatalf("Got error on p.Parse(): %v", err)
+	}
+
+	interp := golox.NewInterpreter()
+	if err = golox.NewResolver(interp).Resolve(stmts); err != nil {
+		t.Fatalf("Got error on r.Resolve(): %v", err)
+	}
+
+	_, err = interp.Interpret(stmts)
+	if err == nil {
+		t.Fatal("Expected a runtime error")
+	}
+
+	// outer calls middle in tail position, so middle replaces its frame.
+	expected := []string{
+		"at fail (line 3)",
+		"at middle (line 6)",
+		"at script (line 12)",
+	}
+
+	if !reflect.DeepEqual(err.Trace, expected) {
+		t.Fatalf("Expected trace %q, got %q", expected, err.Trace)
+	}
+}
@@ -1,7 +1,7 @@
 package golox
 
 type Expr interface {
-	Accept(v ExprVisitor) (interface{}, *LoxError)
+	Accept(v ExprVisitor) (Value, *LoxError)
 }
 
 // ================ Assign ================
@@ -11,7 +11,7 @@ type Assign struct {
 	Value Expr
 }
 
-func (a *Assign) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (a *Assign) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptAssignExpr(a)
 }
 
@@ -23,7 +23,7 @@ type Binary struct {
 	Right    Expr
 }
 
-func (b *Binary) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (b *Binary) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptBinaryExpr(b)
 }
 
@@ -33,17 +33,17 @@ type Grouping struct {
 	Expr Expr
 }
 
-func (g *Grouping) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (g *Grouping) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptGroupingExpr(g)
 }
 
 // ================ Literal ================
 
 type Literal struct {
-	Value interface{}
+	Value Value
 }
 
-func (l *Literal) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (l *Literal) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptLiteralExpr(l)
 }
 
@@ -54,7 +54,7 @@ type Unary struct {
 	Right    Expr
 }
 
-func (u *Unary) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (u *Unary) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptUnaryExpr(u)
 }
 
@@ -66,7 +66,7 @@ type Call struct {
 	Args   []Expr
 }
 
-func (c *Call) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (c *Call) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptCallExpr(c)
 }
 
@@ -76,7 +76,7 @@ type Variable struct {
 	Name Token
 }
 
-func (va *Variable) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (va *Variable) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptVariableExpr(va)
 }
 
@@ -88,19 +88,82 @@ type Logical struct {
 	Right    Expr
 }
 
-func (l *Logical) Accept(v ExprVisitor) (interface{}, *LoxError) {
+func (l *Logical) Accept(v ExprVisitor) (Value, *LoxError) {
 	return v.AcceptLogicalExpr(l)
 }
 
+// ================ Lambda ================
+
+type Lambda struct {
+	Decl *Func
+}
+
+func (l *Lambda) Accept(v ExprVisitor) (Value, *LoxError) {
+	return v.AcceptLambdaExpr(l)
+}
+
+// ================ Get ================
+
+type Get struct {
+	Object Expr
+	Name   Token
+}
+
+func (g *Get) Accept(v ExprVisitor) (Value, *LoxError) {
+	return v.AcceptGetExpr(g)
+}
+
+// ================ Conditional ================
+
+type Conditional struct {
+	Condition Expr
+	Question  Token
+	Then      Expr
+	Else      Expr
+}
+
+func (c *Conditional) Accept(v ExprVisitor) (Value, *LoxError) {
+	return v.AcceptConditionalExpr(c)
+}
+
+// ================ CompoundAssign ================
+
+type CompoundAssign struct {
+	Name     Token
+	Operator Token
+	Value    Expr
+}
+
+func (c *CompoundAssign) Accept(v ExprVisitor) (Value, *LoxError) {
+	return v.AcceptCompoundAssignExpr(c)
+}
+
+// ================ Increment ================
+
+type Increment struct {
+	Name     Token
+	Operator Token
+	Postfix  bool
+}
+
+func (i *Increment) Accept(v ExprVisitor) (Value, *LoxError) {
+	return v.AcceptIncrementExpr(i)
+}
+
 // ================ ExprVisitor ================
 
 type ExprVisitor interface {
-	AcceptAssignExpr(*Assign) (interface{}, *LoxError)
-	AcceptBinaryExpr(*Binary) (interface{}, *LoxError)
-	AcceptGroupingExpr(*Grouping) (interface{}, *LoxError)
-	AcceptLiteralExpr(*Literal) (interface{}, *LoxError)
-	AcceptUnaryExpr(*Unary) (interface{}, *LoxError)
-	AcceptCallExpr(*Call) (interface{}, *LoxError)
-	AcceptVariableExpr(*Variable) (interface{}, *LoxError)
-	AcceptLogicalExpr(*Logical) (interface{}, *LoxError)
+	AcceptAssignExpr(*Assign) (Value, *LoxError)
+	AcceptBinaryExpr(*Binary) (Value, *LoxError)
+	AcceptGroupingExpr(*Grouping) (Value, *LoxError)
+	AcceptLiteralExpr(*Literal) (Value, *LoxError)
+	AcceptUnaryExpr(*Unary) (Value, *LoxError)
+	AcceptCallExpr(*Call) (Value, *LoxError)
+	AcceptVariableExpr(*Variable) (Value, *LoxError)
+	AcceptLogicalExpr(*Logical) (Value, *LoxError)
+	AcceptLambdaExpr(*Lambda) (Value, *LoxError)
+	AcceptGetExpr(*Get) (Value, *LoxError)
+	AcceptConditionalExpr(*Conditional) (Value, *LoxError)
+	AcceptCompoundAssignExpr(*CompoundAssign) (Value, *LoxError)
+	AcceptIncrementExpr(*Increment) (Value, *LoxError)
 }
@@ -1,5 +1,10 @@
 module github.com/agayev169/golox
 
-go 1.16
+go 1.18
 
-require github.com/sirupsen/logrus v1.8.1
+require (
+	github.com/sirupsen/logrus v1.8.1
+	github.com/tetratelabs/wazero v1.2.1
+)
+
+require golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
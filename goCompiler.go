@@ -0,0 +1,660 @@
+package golox
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoCompiler compiles a resolved program to Go source code running it with
+// the rt package, e.g.
+//
+//	fun add(a, b) { return a + b; }
+//	print add(1, 2);
+//
+// becomes
+//
+//	r.DefineGlobal(scriptTok0, r.Function("add", 2, func(args []rt.Value) rt.Value {
+//		a_1, b_2 := args[0], args[1]
+//		return rt.Binary(scriptTok1, a_1, b_2)
+//	}))
+//	r.Print(r.Call(scriptTok2, rt.Callee(scriptTok2, r.Global(scriptTok3), 2), rt.IntValue(1), rt.IntValue(2)))
+//
+// Local variables become Go variables, found through the resolution the
+// Resolver recorded in the Interpreter, so Go closures capture them like Lox
+// closures do. Everything else goes through the operations the Interpreter
+// uses, so the compiled program prints the same output and fails with the
+// same errors.
+type GoCompiler struct {
+	// The package of the generated file and the function running the script.
+	Package string
+	Func    string
+	// Whether to generate a main function running the script as a program.
+	Main bool
+
+	interp *Interpreter
+	scopes []*goScope
+	// The block statements are generated into.
+	block *goBlock
+	// The function being compiled, nil at the top level.
+	fn     *goFunc
+	tokens map[goTokenKey]string
+	// The declarations of the tokens, in the order they were first used.
+	tokenDecls []string
+	names      int
+	usesMath   bool
+}
+
+// goScope is a local scope, holding the variables of its slots.
+type goScope struct {
+	vars []*goVar
+}
+
+type goVar struct {
+	name string
+	// Set if the variable is declared without an initializer, so that it must
+	// be checked to be assigned whenever it is read.
+	unassigned bool
+	read       bool
+	// The statement declaring the variable, nil for function parameters.
+	block *goBlock
+	decl  int
+}
+
+type goBlock struct {
+	stmts []string
+	// Whether the last statement always returns from the Go function, so that
+	// no return is needed after it.
+	returns bool
+}
+
+func (b *goBlock) String() string {
+	return strings.Join(b.stmts, "\n")
+}
+
+type goFunc struct {
+	// The number of clauses of try statements the current statement is in.
+	// They are compiled to Go functions which also report whether they
+	// returned, see rt.Runtime.Try.
+	tryDepth int
+}
+
+type goTokenKey struct {
+	typ       TokenType
+	lexeme    string
+	file      string
+	line, col int
+}
+
+// goTokenTypes are the names of the token types compiled programs need.
+var goTokenTypes = map[TokenType]string{
+	LEFT_PAREN:    "LEFT_PAREN",
+	RIGHT_PAREN:   "RIGHT_PAREN",
+	COMMA:         "COMMA",
+	MINUS:         "MINUS",
+	PLUS:          "PLUS",
+	SLASH:         "SLASH",
+	STAR:          "STAR",
+	PERCENT:       "PERCENT",
+	BANG:          "BANG",
+	BANG_EQUAL:    "BANG_EQUAL",
+	EQUAL_EQUAL:   "EQUAL_EQUAL",
+	GREATER:       "GREATER",
+	GREATER_EQUAL: "GREATER_EQUAL",
+	LESS:          "LESS",
+	LESS_EQUAL:    "LESS_EQUAL",
+	PLUS_PLUS:     "PLUS_PLUS",
+	MINUS_MINUS:   "MINUS_MINUS",
+	TILDE_SLASH:   "TILDE_SLASH",
+	IDENTIFIER:    "IDENTIFIER",
+	INTERPOLATION: "INTERPOLATION",
+	THROW:         "THROW",
+}
+
+// NewGoCompiler returns a GoCompiler generating a standalone program from
+// statements resolved with interp.
+func NewGoCompiler(interp *Interpreter) *GoCompiler {
+	return &GoCompiler{Package: "main", Func: "script", Main: true, interp: interp, tokens: make(map[goTokenKey]string)}
+}
+
+// Compile returns the formatted source of a Go file running stmts.
+func (c *GoCompiler) Compile(stmts []Stmt) ([]byte, error) {
+	body := c.body(func() {
+		for _, s := range stmts {
+			c.stmt(s)
+		}
+	})
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "// Code generated by golox build. DO NOT EDIT.\n\npackage %s\n\nimport (\n", c.Package)
+	if c.usesMath {
+		fmt.Fprintf(&b, "\"math\"\n\n")
+	}
+	fmt.Fprintf(&b, "\"github.com/agayev169/golox/rt\"\n)\n\n")
+
+	if len(c.tokenDecls) > 0 {
+		fmt.Fprintf(&b, "var (\n%s\n)\n\n", strings.Join(c.tokenDecls, "\n"))
+	}
+
+	if c.Main {
+		fmt.Fprintf(&b, "func main() {\nrt.Main(%s)\n}\n\n", c.Func)
+	}
+
+	fmt.Fprintf(&b, "func %s(r *rt.Runtime) {\n%s\n}\n", c.Func, body)
+
+	return format.Source(b.Bytes())
+}
+
+// Statements
+
+func (c *GoCompiler) stmt(s Stmt) {
+	switch st := s.(type) {
+	case *Block:
+		b := c.scope(st.Stmts)
+		c.emit("{\n%s\n}", b)
+		c.block.returns = b.returns
+	case *Expression:
+		c.exprStmt(st.Expr)
+	case *Print:
+		c.emit("r.Print(%s)", c.expr(st.Expr))
+	case *Var:
+		if len(c.scopes) == 0 {
+			c.emit("r.DefineGlobal(%s, %s)", c.token(st.Name), c.initializer(st))
+		} else {
+			c.declare(st.Name, st.Initializer == nil, func() string { return c.initializer(st) })
+		}
+	case *Func:
+		if len(c.scopes) == 0 {
+			c.emit("r.DefineGlobal(%s, %s)", c.token(st.Name), c.function(st))
+		} else {
+			c.declare(st.Name, false, func() string { return c.function(st) })
+		}
+	case *If:
+		cond, body := c.expr(st.Condition), c.branch(st.Body)
+		c.emit("if %s.IsTruthy() {\n%s\n}", cond, body)
+		if st.ElseBody != nil {
+			els := c.branch(st.ElseBody)
+			c.block.stmts[len(c.block.stmts)-1] += fmt.Sprintf(" else {\n%s\n}", els)
+			c.block.returns = body.returns && els.returns
+		}
+	case *While:
+		c.emit("for %s.IsTruthy() {\n%s\n}", c.expr(st.Condition), c.branch(st.Body))
+	case *Return:
+		c.returnStmt(st)
+	case *Throw:
+		c.emit("rt.Throw(%s, %s)", c.token(st.Keyword), c.expr(st.Value))
+	case *Try:
+		c.tryStmt(st)
+	default:
+		panic(fmt.Sprintf("cannot compile %T", s))
+	}
+}
+
+func (c *GoCompiler) exprStmt(e Expr) {
+	switch ex := e.(type) {
+	case *Assign:
+		if v := c.local(ex); v != nil {
+			c.emit("%s = %s", v.name, c.expr(ex.Value))
+
+			return
+		}
+	case *Literal, *Variable, *Grouping:
+		c.emit("_ = %s", c.expr(e))
+
+		return
+	}
+
+	// Everything else compiles to a call.
+	c.emit("%s", c.expr(e))
+}
+
+func (c *GoCompiler) initializer(v *Var) string {
+	if v.Initializer == nil {
+		return "rt.Uninitialized"
+	}
+
+	return c.expr(v.Initializer)
+}
+
+func (c *GoCompiler) returnStmt(st *Return) {
+	val := "rt.NilValue"
+	if call, ok := st.Value.(*Call); ok && c.interp.tailCalls[st] {
+		val = c.call(call, "TailCall")
+	} else if st.Value != nil {
+		val = c.expr(st.Value)
+	}
+
+	if c.fn.tryDepth > 0 {
+		c.emit("return %s, true", val)
+	} else {
+		c.emit("return %s", val)
+	}
+
+	c.block.returns = true
+}
+
+func (c *GoCompiler) tryStmt(st *Try) {
+	if c.fn != nil {
+		c.fn.tryDepth++
+	}
+
+	clause := "func(%s) (rt.Value, bool) {\n%s\n}"
+	ret := "return rt.NilValue, false"
+	body, catch, finally := fmt.Sprintf(clause, "", withReturn(c.scope(st.Body), ret)), "nil", "nil"
+
+	if st.CatchBody != nil {
+		c.beginScope()
+		param := c.addVar(st.CatchName, false).name + " rt.Value"
+		catch = fmt.Sprintf(clause, param, withReturn(c.body(func() { c.stmts(st.CatchBody) }), ret))
+		c.endScope()
+	}
+
+	if st.FinallyBody != nil {
+		finally = fmt.Sprintf(clause, "", withReturn(c.scope(st.FinallyBody), ret))
+	}
+
+	try := fmt.Sprintf("r.Try(%s, %s, %s)", body, catch, finally)
+
+	if c.fn == nil {
+		c.emit("%s", try)
+
+		return
+	}
+
+	if c.fn.tryDepth--; c.fn.tryDepth > 0 {
+		c.emit("if v, ok := %s; ok {\nreturn v, true\n}", try)
+	} else {
+		c.emit("if v, ok := %s; ok {\nreturn v\n}", try)
+	}
+}
+
+// function compiles a function declaration to an rt.Function.
+func (c *GoCompiler) function(f *Func) string {
+	fn := c.fn
+	c.fn = &goFunc{}
+	defer func() { c.fn = fn }()
+
+	body := c.body(func() {
+		c.beginScope()
+		defer c.endScope()
+
+		if len(f.Params) > 0 {
+			names, args := make([]string, 0, len(f.Params)), make([]string, 0, len(f.Params))
+			for i, p := range f.Params {
+				v := c.addVar(p, false)
+				v.block, v.decl = c.block, len(c.block.stmts)
+				names, args = append(names, v.name), append(args, fmt.Sprintf("args[%d]", i))
+			}
+
+			c.emit("%s := %s", strings.Join(names, ", "), strings.Join(args, ", "))
+		}
+
+		c.stmts(f.Body)
+	})
+
+	return fmt.Sprintf("r.Function(%s, %d, func(args []rt.Value) rt.Value {\n%s\n})",
+		strconv.Quote(f.Name.Lexeme), len(f.Params), withReturn(body, "return rt.NilValue"))
+}
+
+// withReturn appends the statement ret to the body of a Go function unless
+// it already ends with a return.
+func withReturn(body *goBlock, ret string) string {
+	if body.returns {
+		return body.String()
+	}
+
+	return body.String() + "\n" + ret
+}
+
+func (c *GoCompiler) stmts(ss []Stmt) {
+	for _, s := range ss {
+		c.stmt(s)
+	}
+}
+
+// scope compiles stmts in a new scope.
+func (c *GoCompiler) scope(stmts []Stmt) *goBlock {
+	return c.body(func() {
+		c.beginScope()
+		defer c.endScope()
+
+		c.stmts(stmts)
+	})
+}
+
+// branch compiles the body of an if or a while statement.
+func (c *GoCompiler) branch(s Stmt) *goBlock {
+	if b, ok := s.(*Block); ok {
+		return c.scope(b.Stmts)
+	}
+
+	return c.body(func() { c.stmt(s) })
+}
+
+// Expressions
+
+func (c *GoCompiler) expr(e Expr) string {
+	switch ex := e.(type) {
+	case *Literal:
+		return c.literal(ex.Value)
+	case *Grouping:
+		return c.expr(ex.Expr)
+	case *Variable:
+		return c.variable(ex, ex.Name)
+	case *Assign:
+		if v := c.local(ex); v != nil {
+			return fmt.Sprintf("rt.Set(&%s, %s)", v.name, c.expr(ex.Value))
+		}
+
+		return fmt.Sprintf("r.SetGlobal(%s, %s)", c.token(ex.Name), c.expr(ex.Value))
+	case *Binary:
+		ops := c.operands(ex.Left, ex.Right)
+
+		return fmt.Sprintf("rt.Binary(%s, %s, %s)", c.token(ex.Operator), ops[0], ops[1])
+	case *Unary:
+		return fmt.Sprintf("rt.Unary(%s, %s)", c.token(ex.Operator), c.expr(ex.Right))
+	case *Call:
+		return c.call(ex, "Call")
+	case *Logical:
+		cond := "v.IsTruthy()"
+		if ex.Operator.Type == AND {
+			cond = "!" + cond
+		}
+
+		return fmt.Sprintf("func() rt.Value {\nif v := %s; %s {\nreturn v\n}\nreturn %s\n}()",
+			c.expr(ex.Left), cond, c.expr(ex.Right))
+	case *Conditional:
+		return fmt.Sprintf("func() rt.Value {\nif %s.IsTruthy() {\nreturn %s\n}\nreturn %s\n}()",
+			c.expr(ex.Condition), c.expr(ex.Then), c.expr(ex.Else))
+	case *Lambda:
+		return c.function(ex.Decl)
+	case *Get:
+		return fmt.Sprintf("rt.Get(%s, %s)", c.token(ex.Name), c.expr(ex.Object))
+	case *CompoundAssign:
+		return c.compoundAssign(ex)
+	case *Increment:
+		if v := c.local(ex); v != nil {
+			return fmt.Sprintf("rt.Increment(%s, &%s, %s, %t)", c.token(ex.Operator), v.name, c.variable(ex, ex.Name), ex.Postfix)
+		}
+
+		return fmt.Sprintf("r.IncrementGlobal(%s, %s, %t)", c.token(ex.Name), c.token(ex.Operator), ex.Postfix)
+	}
+
+	panic(fmt.Sprintf("cannot compile %T", e))
+}
+
+func (c *GoCompiler) literal(v Value) string {
+	switch v.Type() {
+	case NilType:
+		return "rt.NilValue"
+	case BoolType:
+		return fmt.Sprintf("%s(%t)", "rt.BoolValue", v.AsBool())
+	case IntType:
+		if v.IsBigInt() {
+			return fmt.Sprintf("rt.BigInt(%q)", v.AsBigInt().String())
+		}
+
+		return fmt.Sprintf("%s(%d)", "rt.IntValue", v.AsInt())
+	case NumberType:
+		return fmt.Sprintf("%s(%s)", "rt.NumberValue", c.float(v.AsNumber()))
+	case StringType:
+		return fmt.Sprintf("%s(%s)", "rt.StringValue", strconv.Quote(v.AsString()))
+	}
+
+	panic(fmt.Sprintf("cannot compile a literal of type %s", v.Type()))
+}
+
+// float returns a Go expression for n, which may be a result of folding that
+// no literal can express.
+func (c *GoCompiler) float(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		c.usesMath = true
+
+		return "math.NaN()"
+	case math.IsInf(n, 0):
+		c.usesMath = true
+
+		return fmt.Sprintf("math.Inf(%d)", int(math.Copysign(1, n)))
+	case n == 0 && math.Signbit(n):
+		c.usesMath = true
+
+		return "math.Copysign(0, -1)"
+	}
+
+	s := strconv.FormatFloat(n, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+// variable compiles reading the variable name that expr refers to.
+func (c *GoCompiler) variable(expr Expr, name Token) string {
+	v := c.local(expr)
+	if v == nil {
+		return fmt.Sprintf("r.Global(%s)", c.token(name))
+	}
+
+	v.read = true
+
+	if v.unassigned {
+		return fmt.Sprintf("rt.Assigned(%s, %s)", c.token(name), v.name)
+	}
+
+	return v.name
+}
+
+func (c *GoCompiler) compoundAssign(ca *CompoundAssign) string {
+	op := ca.Operator
+	op.Type = compoundOperators[op.Type]
+
+	v := c.local(ca)
+	if v == nil {
+		name := c.token(ca.Name)
+
+		return fmt.Sprintf("r.SetGlobal(%s, rt.Binary(%s, r.Global(%s), %s))", name, c.token(op), name, c.expr(ca.Value))
+	}
+
+	cur := c.variable(ca, ca.Name)
+	if !v.unassigned && hasSideEffects(ca.Value) {
+		cur = fmt.Sprintf("rt.Read(%s)", cur)
+	}
+
+	return fmt.Sprintf("rt.Set(&%s, rt.Binary(%s, %s, %s))", v.name, c.token(op), cur, c.expr(ca.Value))
+}
+
+// call compiles a call made with the given method of rt.Runtime. The callee
+// is checked before the arguments are evaluated.
+func (c *GoCompiler) call(call *Call, method string) string {
+	paren := c.token(call.Paren)
+	res := fmt.Sprintf("r.%s(%s, rt.Callee(%s, %s, %d)", method, paren, paren, c.expr(call.Callee), len(call.Args))
+
+	for _, arg := range c.operands(call.Args...) {
+		res += ", " + arg
+	}
+
+	return res + ")"
+}
+
+// operands compiles expressions evaluated from left to right. Go evaluates
+// the calls in an expression in order, but may read a variable after a call
+// to its right, which could have assigned it. Such reads are made calls too.
+func (c *GoCompiler) operands(es ...Expr) []string {
+	res := make([]string, 0, len(es))
+
+	for i, e := range es {
+		s := c.expr(e)
+		if g, ok := e.(*Grouping); ok {
+			e = g.Expr
+		}
+
+		if v, ok := e.(*Variable); ok && c.local(v) != nil && !c.local(v).unassigned && hasSideEffects(es[i+1:]...) {
+			s = fmt.Sprintf("rt.Read(%s)", s)
+		}
+
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// hasSideEffects reports whether evaluating any of es may assign a variable.
+func hasSideEffects(es ...Expr) bool {
+	for _, e := range es {
+		switch ex := e.(type) {
+		case *Assign, *CompoundAssign, *Increment, *Call:
+			return true
+		case *Grouping:
+			if hasSideEffects(ex.Expr) {
+				return true
+			}
+		case *Unary:
+			if hasSideEffects(ex.Right) {
+				return true
+			}
+		case *Binary:
+			if hasSideEffects(ex.Left, ex.Right) {
+				return true
+			}
+		case *Logical:
+			if hasSideEffects(ex.Left, ex.Right) {
+				return true
+			}
+		case *Conditional:
+			if hasSideEffects(ex.Condition, ex.Then, ex.Else) {
+				return true
+			}
+		case *Get:
+			if hasSideEffects(ex.Object) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Scopes and names
+
+// local returns the local variable expr refers to, or nil if it refers to a
+// global one.
+func (c *GoCompiler) local(expr Expr) *goVar {
+	l, ok := c.interp.locals[expr]
+	if !ok {
+		return nil
+	}
+
+	return c.scopes[len(c.scopes)-1-l.depth].vars[l.slot]
+}
+
+// declare declares a local variable initialized with the expression init
+// returns. The variable is declared before the expression is compiled, since
+// functions in it may refer to the variable.
+func (c *GoCompiler) declare(name Token, unassigned bool, init func() string) {
+	v := c.addVar(name, unassigned)
+	v.block, v.decl = c.block, len(c.block.stmts)
+
+	if val := init(); v.read {
+		c.emit("var %s rt.Value\n%s = %s", v.name, v.name, val)
+	} else {
+		c.emit("%s := %s", v.name, val)
+	}
+
+	// Reads of the variable in its own initializer do not count, since the
+	// declaration is needed for them.
+	v.read = false
+}
+
+func (c *GoCompiler) addVar(name Token, unassigned bool) *goVar {
+	sc := c.scopes[len(c.scopes)-1]
+	v := &goVar{name: c.newName(name), unassigned: unassigned}
+	sc.vars = append(sc.vars, v)
+
+	return v
+}
+
+// newName returns a unique Go identifier for the Lox variable name.
+func (c *GoCompiler) newName(name Token) string {
+	c.names++
+
+	ident := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return r
+		}
+
+		return -1
+	}, name.Lexeme)
+
+	if ident == "" {
+		ident = "v"
+	}
+
+	return fmt.Sprintf("%s_%d", ident, c.names)
+}
+
+func (c *GoCompiler) beginScope() {
+	c.scopes = append(c.scopes, &goScope{})
+}
+
+// endScope leaves the current scope, marking the variables that are never
+// read as used, which Go requires.
+func (c *GoCompiler) endScope() {
+	for _, v := range c.scopes[len(c.scopes)-1].vars {
+		if !v.read && v.block != nil {
+			v.block.stmts[v.decl] += "\n_ = " + v.name
+		}
+	}
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// body returns the block of the statements f generates.
+func (c *GoCompiler) body(f func()) *goBlock {
+	block := c.block
+	c.block = &goBlock{}
+	defer func() { c.block = block }()
+
+	f()
+
+	return c.block
+}
+
+func (c *GoCompiler) emit(format string, args ...interface{}) {
+	c.block.stmts = append(c.block.stmts, fmt.Sprintf(format, args...))
+	c.block.returns = false
+}
+
+// token returns the name of a package-level variable holding t, for the
+// errors reported at its position.
+func (c *GoCompiler) token(t Token) string {
+	key := goTokenKey{typ: t.Type, lexeme: t.Lexeme, file: t.File, line: t.Line, col: t.Col}
+	if name, ok := c.tokens[key]; ok {
+		return name
+	}
+
+	typ, ok := goTokenTypes[t.Type]
+	if ok {
+		typ = "rt." + typ
+	} else {
+		typ = fmt.Sprintf("rt.TokenType(%d)", int(t.Type))
+	}
+
+	file := ""
+	if t.File != "" {
+		file = fmt.Sprintf(", File: %q", t.File)
+	}
+
+	name := fmt.Sprintf("%sTok%d", c.Func, len(c.tokenDecls))
+	c.tokens[key] = name
+	c.tokenDecls = append(c.tokenDecls, fmt.Sprintf("%s = rt.Token{Type: %s, Lexeme: %q%s, Line: %d, Col: %d}",
+		name, typ, t.Lexeme, file, t.Line, t.Col))
+
+	return name
+}
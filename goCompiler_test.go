@@ -0,0 +1,169 @@
+package golox_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+const compiledMain = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/agayev169/golox/rt"
+)
+
+func main() {
+	if err := rt.New(os.Stdout).Run(scripts[os.Args[1]]); err != nil {
+		fmt.Fprintf(os.Stderr, "%%d %%s", err.Line, err.Msg)
+		os.Exit(70)
+	}
+}
+
+var scripts = map[string]func(*rt.Runtime){
+%s}
+`
+
+// TestGoCompiler compiles every conformance test into one Go program and
+// checks that running it behaves like the interpreter.
+func TestGoCompiler(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	// The program must be in the module to import it. Go ignores directories
+	// starting with _ in patterns like ./...
+	dir, err := os.MkdirTemp(".", "_gocompiler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var scripts strings.Builder
+	index := make(map[string]int)
+	for i, path := range conformanceFiles(t) {
+		index[path] = i
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stmts, interp, lerr := parseAndResolve(src)
+		if lerr != nil {
+			continue
+		}
+
+		c := golox.NewGoCompiler(interp)
+		c.Func, c.Main = fmt.Sprintf("script%d", i), false
+
+		code, err := c.Compile(golox.NewOptimizer().Optimize(stmts))
+		if err != nil {
+			t.Fatalf("Failed to compile %s: %v", path, err)
+		}
+
+		if err = os.WriteFile(filepath.Join(dir, c.Func+".go"), code, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		fmt.Fprintf(&scripts, "\"%d\": %s,\n", i, c.Func)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(compiledMain, scripts.String())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := filepath.Join(dir, "scripts")
+	if out, err := exec.Command("go", "build", "-o", bin, "./"+dir).CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+
+	forEachConformanceFile(t, func(t *testing.T, path string, src []byte) {
+		res := golox.RunTestSourceWith(path, src, func(src []byte, out *bytes.Buffer) (compileErr, runtimeErr *golox.LoxError) {
+			if _, _, lerr := parseAndResolve(src); lerr != nil {
+				return lerr, nil
+			}
+
+			return nil, runCompiled(t, bin, index[path], out)
+		})
+
+		for _, f := range res.Failures {
+			t.Error(f)
+		}
+	})
+}
+
+// TestGoCompilerReturns checks that no return is added after function
+// bodies that always return already, which go vet reports as unreachable.
+func TestGoCompilerReturns(t *testing.T) {
+	for src, returns := range map[string]bool{
+		"fun f(n) { if (n) { return 1; } else { return 2; } }":                                  true,
+		"fun f(n) { { if (n) return 1; else return 2; } }":                                      true,
+		"fun f(n) { if (n) return 1; else if (!n) return 2; else return fun() { return 3; }; }": true,
+		"fun f(n) { if (n) return 1; }":                                                         false,
+		"fun f(n) { if (n) return 1; else { print 2; } }":                                       false,
+		"fun f(n) { while (n) return 1; }":                                                      false,
+	} {
+		stmts, interp, lerr := parseAndResolve([]byte(src))
+		if lerr != nil {
+			t.Fatalf("%s: %v", src, lerr)
+		}
+
+		code, err := golox.NewGoCompiler(interp).Compile(stmts)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+
+		if added := strings.Contains(string(code), "return rt.NilValue"); added == returns {
+			t.Errorf("%s: expected a return to be added: %t\n%s", src, !returns, code)
+		}
+	}
+}
+
+// TestGoCompilerImports checks that compiled programs only depend on the
+// runtime package, not on the interpreter.
+func TestGoCompilerImports(t *testing.T) {
+	stmts, interp, lerr := parseAndResolve([]byte("fun f(a) { return a + 1; }\nprint f(1.5) + 100000000000000000000;\ntry { throw f; } catch (e) { print e; }\n"))
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	code, err := golox.NewGoCompiler(interp).Compile(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(code), `"github.com/agayev169/golox"`) {
+		t.Errorf("Expected the program to import only the rt package\n%s", code)
+	}
+}
+
+// runCompiled runs the i-th script of the compiled program bin and returns
+// the runtime error it reported, if any.
+func runCompiled(t *testing.T, bin string, i int, out *bytes.Buffer) *golox.LoxError {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(bin, strconv.Itoa(i))
+	cmd.Stdout, cmd.Stderr = out, &stderr
+
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+
+	parts := strings.SplitN(stderr.String(), " ", 2)
+	line, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) != 2 {
+		t.Fatalf("Unexpected failure: %s", stderr.String())
+	}
+
+	return &golox.LoxError{Line: line, Msg: parts[1]}
+}
@@ -1,34 +1,78 @@
 package golox
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+)
 
 type Interpreter struct {
 	globEnv *Env
 	env     *Env
-	locals  map[Expr]int
+	locals  map[Expr]local
+	// The returns of calls in tail position, see AcceptReturnStmt.
+	tailCalls map[*Return]bool
+	out       io.Writer
+	// In REPL mode the values of top-level expression statements are printed.
+	repl bool
+	// Set when profiling, see SetProfiler.
+	profiler *Profiler
+	// Set when recording coverage, see SetCoverage.
+	coverage *Coverage
+	// The Lox functions being called, innermost last.
+	frames []CallFrame
+}
+
+// local is the position of a local variable resolved by the Resolver.
+type local struct {
+	depth int
+	slot  int
 }
 
 func NewInterpreter() *Interpreter {
-	env := NewEnv(nil)
+	env := NewGlobalEnv()
+
+	return &Interpreter{globEnv: env, env: env, locals: make(map[Expr]local), tailCalls: make(map[*Return]bool), out: os.Stdout}
+}
+
+// Globals returns the values of all global variables and functions by name.
+func (interp *Interpreter) Globals() map[string]Value {
+	vars := interp.globEnv.Vars()
 
-	if err := addFunc(env, Token{Lexeme: "clock", Type: IDENTIFIER}, &LoxClock{}); err != nil {
-		panic(err)
+	res := make(map[string]Value, len(vars))
+	for name, val := range vars {
+		res[name] = val
 	}
 
-	return &Interpreter{globEnv: env, env: env, locals: make(map[Expr]int)}
+	return res
+}
+
+// SetREPLMode enables or disables echoing the values of top-level expression
+// statements, e.g. `1 + 2;` prints 3 while `var a = 1;` prints nothing.
+func (interp *Interpreter) SetREPLMode(repl bool) {
+	interp.repl = repl
+}
+
+// SetOutput redirects the output of print statements to w.
+func (interp *Interpreter) SetOutput(w io.Writer) {
+	interp.out = w
 }
 
 func addFunc(env *Env, name Token, f Callable) *LoxError {
-	return env.Define(name, f)
+	return env.Define(name, ObjectValue(f))
 }
 
-func (interp *Interpreter) Interpret(stmts []Stmt) (interface{}, *LoxError) {
-	var res interface{}
+func (interp *Interpreter) Interpret(stmts []Stmt) (Value, *LoxError) {
+	var res Value
+
+	if interp.coverage != nil {
+		interp.coverage.register(stmts)
+	}
 
 	for _, stmt := range stmts {
-		v, err := stmt.Accept(interp)
+		v, err := interp.execute(stmt)
 		if err != nil {
-			return nil, err
+			return NilValue, err
 		}
 
 		res = v
@@ -37,45 +81,45 @@ func (interp *Interpreter) Interpret(stmts []Stmt) (interface{}, *LoxError) {
 	return res, nil
 }
 
-func (interp *Interpreter) AcceptFuncStmt(f *Func) (interface{}, *LoxError) {
-	if err := addFunc(interp.env, f.Name, NewLoxFunction(f, interp.env)); err != nil {
-		return nil, err
+func (interp *Interpreter) AcceptFuncStmt(f *Func) (Value, *LoxError) {
+	if err := addFunc(interp.env, f.Name, NewLoxFunction(f, interp.env, interp)); err != nil {
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (interp *Interpreter) AcceptExpressionStmt(expr *Expression) (interface{}, *LoxError) {
+func (interp *Interpreter) AcceptExpressionStmt(expr *Expression) (Value, *LoxError) {
 	res, err := interp.evaluate(expr.Expr)
 	if err != nil {
-		return nil, err
+		return NilValue, err
+	}
+
+	if interp.repl && interp.env == interp.globEnv {
+		fmt.Fprintln(interp.out, Stringify(res))
 	}
 
 	return res, nil
 }
 
-func (interp *Interpreter) AcceptPrintStmt(expr *Print) (interface{}, *LoxError) {
+func (interp *Interpreter) AcceptPrintStmt(expr *Print) (Value, *LoxError) {
 	val, err := interp.evaluate(expr.Expr)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	if _, ok := val.(Nil); ok || val == nil {
-		fmt.Println("nil")
-	} else {
-		fmt.Println(val)
-	}
+	fmt.Fprintln(interp.out, Stringify(val))
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (interp *Interpreter) AcceptBlockStmt(b *Block) (interface{}, *LoxError) {
+func (interp *Interpreter) AcceptBlockStmt(b *Block) (Value, *LoxError) {
 	err := interp.ExecuteBlock(b.Stmts, NewEnv(interp.env))
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
 func (interp *Interpreter) ExecuteBlock(ss []Stmt, env *Env) *LoxError {
@@ -86,7 +130,7 @@ func (interp *Interpreter) ExecuteBlock(ss []Stmt, env *Env) *LoxError {
 	}()
 
 	for _, s := range ss {
-		_, err := s.Accept(interp)
+		_, err := interp.execute(s)
 		if err != nil {
 			return err
 		}
@@ -95,15 +139,23 @@ func (interp *Interpreter) ExecuteBlock(ss []Stmt, env *Env) *LoxError {
 	return nil
 }
 
-func (interp *Interpreter) AcceptIfStmt(iff *If) (interface{}, *LoxError) {
+func (interp *Interpreter) AcceptIfStmt(iff *If) (Value, *LoxError) {
 	cond, err := interp.evaluate(iff.Condition)
 	if err != nil {
-		return nil, err
+		return NilValue, err
+	}
+
+	if interp.coverage != nil {
+		if cond.IsTruthy() {
+			interp.coverage.branch(iff, 0)
+		} else {
+			interp.coverage.branch(iff, 1)
+		}
 	}
 
-	if interp.isTruthy(cond) {
+	if cond.IsTruthy() {
 		if res, err2 := interp.execute(iff.Body); err2 != nil {
-			return nil, err2
+			return NilValue, err2
 		} else {
 			return res, nil
 		}
@@ -113,55 +165,78 @@ func (interp *Interpreter) AcceptIfStmt(iff *If) (interface{}, *LoxError) {
 		return interp.execute(iff.ElseBody)
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (interp *Interpreter) AcceptWhileStmt(w *While) (interface{}, *LoxError) {
+func (interp *Interpreter) AcceptWhileStmt(w *While) (Value, *LoxError) {
 	for {
 		cond, err := interp.evaluate(w.Condition)
 		if err != nil {
-			return nil, err
+			return NilValue, err
 		}
 
-		if !interp.isTruthy(cond) {
+		if !cond.IsTruthy() {
+			if interp.coverage != nil {
+				interp.coverage.branch(w, 1)
+			}
+
 			break
 		}
 
+		if interp.coverage != nil {
+			interp.coverage.branch(w, 0)
+		}
+
 		_, err2 := interp.execute(w.Body)
 		if err2 != nil {
-			return nil, err2
+			return NilValue, err2
 		}
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (interp *Interpreter) AcceptVarStmt(v *Var) (interface{}, *LoxError) {
-	var init interface{} = nil
+func (interp *Interpreter) AcceptVarStmt(v *Var) (Value, *LoxError) {
+	init := Uninitialized
 
 	if v.Initializer != nil {
 		val, err := interp.evaluate(v.Initializer)
 		if err != nil {
-			return nil, err
+			return NilValue, err
 		}
 
 		init = val
 	}
 
 	if err := interp.env.Define(v.Name, init); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (interp *Interpreter) AcceptReturnStmt(r *Return) (interface{}, *LoxError) {
-	var ret interface{} = nil
+func (interp *Interpreter) AcceptReturnStmt(r *Return) (Value, *LoxError) {
+	ret := NilValue
+
+	if c, ok := r.Value.(*Call); ok && interp.tailCalls[r] {
+		cf, args, err := interp.evaluateCall(c)
+		if err != nil {
+			return NilValue, err
+		}
+
+		// Lox functions are called by the caller of the current function once
+		// it has returned, so that tail calls do not grow the Go stack.
+		if f, ok := cf.(*LoxFunction); ok {
+			panic(NewTailCall(f, args))
+		}
 
-	if r.Value != nil {
+		if ret, err = interp.call(c, cf, args); err != nil {
+			return NilValue, err
+		}
+	} else if r.Value != nil {
 		r, err := interp.evaluate(r.Value)
 		if err != nil {
-			return nil, err
+			return NilValue, err
 		}
 
 		ret = r
@@ -170,285 +245,327 @@ func (interp *Interpreter) AcceptReturnStmt(r *Return) (interface{}, *LoxError)
 	panic(NewReturn(ret))
 }
 
-func (interp *Interpreter) AcceptAssignExpr(a *Assign) (interface{}, *LoxError) {
-	val, err := interp.evaluate(a.Value)
+func (interp *Interpreter) AcceptThrowStmt(t *Throw) (Value, *LoxError) {
+	val, err := interp.evaluate(t.Value)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	if d, ok := interp.locals[a]; ok {
-		if err = interp.env.AssignAt(a.Name, val, d); err != nil {
-			return nil, err
-		}
-	} else {
-		if err = interp.globEnv.Assign(a.Name, val); err != nil {
-			return nil, err
-		}
+	return NilValue, ThrowValue(t.Keyword, val)
+}
+
+// AcceptTryStmt runs the finally clause however the try statement is left,
+// including by a return, whose panic is resumed once the clause has run. An
+// error in the finally clause replaces the one being propagated.
+func (interp *Interpreter) AcceptTryStmt(t *Try) (res Value, err *LoxError) {
+	if t.FinallyBody != nil {
+		defer func() {
+			r := recover()
+			if _, ok := r.(*Control); r != nil && !ok {
+				panic(r)
+			}
+
+			if ferr := interp.ExecuteBlock(t.FinallyBody, NewEnv(interp.env)); ferr != nil {
+				res, err = NilValue, ferr
+				return
+			}
+
+			if r != nil {
+				panic(r)
+			}
+		}()
 	}
 
-	return nil, nil
-}
+	err = interp.ExecuteBlock(t.Body, NewEnv(interp.env))
+	if err == nil || t.CatchBody == nil {
+		return NilValue, err
+	}
 
-func (interp *Interpreter) AcceptLiteralExpr(l *Literal) (interface{}, *LoxError) {
-	return l.Value, nil
+	env := NewEnv(interp.env)
+	if err = env.Define(t.CatchName, CaughtValue(err)); err != nil {
+		return NilValue, err
+	}
+
+	return NilValue, interp.ExecuteBlock(t.CatchBody, env)
 }
 
-func (interp *Interpreter) AcceptGroupingExpr(g *Grouping) (interface{}, *LoxError) {
-	return g.Expr.Accept(interp)
+func (interp *Interpreter) AcceptAssignExpr(a *Assign) (Value, *LoxError) {
+	val, err := interp.evaluate(a.Value)
+	if err != nil {
+		return NilValue, err
+	}
+
+	if err = interp.assignVariable(a, a.Name, val); err != nil {
+		return NilValue, err
+	}
+
+	return val, nil
 }
 
-func (interp *Interpreter) AcceptUnaryExpr(u *Unary) (interface{}, *LoxError) {
-	v, err := u.Right.Accept(interp)
+// compoundOperators maps the operators of compound assignments to the binary
+// operators they apply.
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:    PLUS,
+	MINUS_EQUAL:   MINUS,
+	STAR_EQUAL:    STAR,
+	SLASH_EQUAL:   SLASH,
+	PERCENT_EQUAL: PERCENT,
+}
 
+func (interp *Interpreter) AcceptCompoundAssignExpr(c *CompoundAssign) (Value, *LoxError) {
+	cur, err := interp.lookUpVariable(c, c.Name)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	switch u.Operator.Type {
-	case MINUS:
-		err = interp.checkNumberOperand(u.Operator, v)
+	val, err := interp.evaluate(c.Value)
+	if err != nil {
+		return NilValue, err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	op := c.Operator
+	op.Type = compoundOperators[op.Type]
 
-		return -(v.(float64)), nil
-	case BANG:
-		return !interp.isTruthy(v), nil
+	if val, err = BinaryOp(op, cur, val); err != nil {
+		return NilValue, err
 	}
 
-	return nil, &LoxError{
-		Number: UnexpectedChar, File: u.Operator.File, Line: u.Operator.Line, Col: u.Operator.Col,
-		Msg: fmt.Sprintf("Unsupported operator type `%s`.", u.Operator.Lexeme),
+	if err = interp.assignVariable(c, c.Name, val); err != nil {
+		return NilValue, err
 	}
+
+	return val, nil
 }
 
-func (interp *Interpreter) AcceptCallExpr(c *Call) (interface{}, *LoxError) {
-	callee, err := interp.evaluate(c.Callee)
+// AcceptIncrementExpr evaluates `++a`, `a++`, `--a` and `a--`. The postfix
+// forms return the value the variable had before.
+func (interp *Interpreter) AcceptIncrementExpr(i *Increment) (Value, *LoxError) {
+	cur, err := interp.lookUpVariable(i, i.Name)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	cf, ok := callee.(Callable)
-	if !ok {
-		return nil, genError(c.Paren, InvalidCall, "Can only call functions and classes.")
+	val, err := IncrementOp(i.Operator, cur)
+	if err != nil {
+		return NilValue, err
 	}
 
-	if cf.GetArity() != len(c.Args) {
-		return nil, genError(c.Paren, InvalidArity,
-			fmt.Sprintf("Expected %d arguments but got %d.", cf.GetArity(), len(c.Args)))
+	if err = interp.assignVariable(i, i.Name, val); err != nil {
+		return NilValue, err
 	}
 
-	args := make([]interface{}, 0, len(c.Args))
-	for _, arg := range c.Args {
-		a, err2 := interp.evaluate(arg)
-		if err2 != nil {
-			return nil, err2
-		}
-
-		args = append(args, a)
+	if i.Postfix {
+		return cur, nil
 	}
 
-	return cf.Call(interp, args)
+	return val, nil
 }
 
-func (interp *Interpreter) AcceptBinaryExpr(b *Binary) (interface{}, *LoxError) {
-	lv, err := b.Left.Accept(interp)
-
+func (interp *Interpreter) AcceptConditionalExpr(c *Conditional) (Value, *LoxError) {
+	cond, err := interp.evaluate(c.Condition)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	rv, err := b.Right.Accept(interp)
+	if cond.IsTruthy() {
+		if interp.coverage != nil {
+			interp.coverage.branch(c, 0)
+		}
 
-	if err != nil {
-		return nil, err
+		return interp.evaluate(c.Then)
 	}
 
-	switch b.Operator.Type {
-	case MINUS:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+	if interp.coverage != nil {
+		interp.coverage.branch(c, 1)
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	return interp.evaluate(c.Else)
+}
 
-		return lv.(float64) - rv.(float64), nil
-	case STAR:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+func (interp *Interpreter) AcceptLiteralExpr(l *Literal) (Value, *LoxError) {
+	return l.Value, nil
+}
 
-		if err != nil {
-			return nil, err
-		}
+func (interp *Interpreter) AcceptGroupingExpr(g *Grouping) (Value, *LoxError) {
+	return g.Expr.Accept(interp)
+}
 
-		return lv.(float64) * rv.(float64), nil
-	case SLASH:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+func (interp *Interpreter) AcceptUnaryExpr(u *Unary) (Value, *LoxError) {
+	v, err := u.Right.Accept(interp)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return NilValue, err
+	}
 
-		return lv.(float64) / rv.(float64), nil
-	case PLUS:
-		if lvv, ok := lv.(float64); ok {
-			if rvv, ok := rv.(float64); ok {
-				return lvv + rvv, nil
-			}
-		}
+	return UnaryOp(u.Operator, v)
+}
 
-		if lvv, ok := lv.(string); ok {
-			if rvv, ok := rv.(string); ok {
-				return lvv + rvv, nil
-			}
-		}
+func (interp *Interpreter) AcceptCallExpr(c *Call) (Value, *LoxError) {
+	cf, args, err := interp.evaluateCall(c)
+	if err != nil {
+		return NilValue, err
+	}
 
-		return nil, &LoxError{
-			Number: UnexpectedChar, File: b.Operator.File, Line: b.Operator.Line, Col: b.Operator.Col,
-			Msg: "Operands must be two numbers or two strings.",
-		}
-	case GREATER:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+	return interp.call(c, cf, args)
+}
 
-		if err != nil {
-			return nil, err
-		}
+// evaluateCall evaluates the callee and the arguments of a call.
+func (interp *Interpreter) evaluateCall(c *Call) (Callable, []Value, *LoxError) {
+	callee, err := interp.evaluate(c.Callee)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		return lv.(float64) > rv.(float64), nil
-	case GREATER_EQUAL:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+	cf, err := CheckCallee(c.Paren, callee, len(c.Args))
+	if err != nil {
+		return nil, nil, err
+	}
 
-		if err != nil {
-			return nil, err
+	args := make([]Value, 0, len(c.Args))
+	for _, arg := range c.Args {
+		a, err2 := interp.evaluate(arg)
+		if err2 != nil {
+			return nil, nil, err2
 		}
 
-		return lv.(float64) >= rv.(float64), nil
-	case LESS:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+		args = append(args, a)
+	}
+
+	return cf, args, nil
+}
 
-		if err != nil {
-			return nil, err
+func (interp *Interpreter) call(c *Call, cf Callable, args []Value) (Value, *LoxError) {
+	f, ok := cf.(*LoxFunction)
+	if !ok {
+		res, err := cf.Call(args)
+		if err != nil && err.Line == 0 {
+			// Errors of native functions do not know where they were called from.
+			err.File, err.Line, err.Col = c.Paren.File, c.Paren.Line, c.Paren.Col
 		}
 
-		return lv.(float64) < rv.(float64), nil
-	case LESS_EQUAL:
-		err := interp.checkNumberOperands(b.Operator, lv, rv)
+		return res, err
+	}
+
+	interp.frames = append(interp.frames, CallFrame{Name: f.decl.Name.Lexeme, Line: c.Paren.Line})
+	res, err := f.call(interp, args)
+	if err != nil && err.Trace == nil {
+		err.Trace = StackTrace(interp.frames, err.Line)
+	}
+	interp.frames = interp.frames[:len(interp.frames)-1]
 
-		if err != nil {
-			return nil, err
-		}
+	return res, err
+}
 
-		return lv.(float64) <= rv.(float64), nil
-	case BANG_EQUAL:
-		return !interp.isEqual(lv, rv), nil
-	case EQUAL_EQUAL:
-		return interp.isEqual(lv, rv), nil
+func (interp *Interpreter) AcceptBinaryExpr(b *Binary) (Value, *LoxError) {
+	lv, err := b.Left.Accept(interp)
+
+	if err != nil {
+		return NilValue, err
 	}
 
-	return nil, &LoxError{
-		Number: UnexpectedChar, File: b.Operator.File, Line: b.Operator.Line, Col: b.Operator.Col,
-		Msg: fmt.Sprintf("Unsupported operator type `%s`.", b.Operator.Lexeme),
+	rv, err := b.Right.Accept(interp)
+
+	if err != nil {
+		return NilValue, err
 	}
+
+	return BinaryOp(b.Operator, lv, rv)
 }
 
-func (interp *Interpreter) AcceptVariableExpr(v *Variable) (interface{}, *LoxError) {
-	var val interface{}
+func (interp *Interpreter) AcceptVariableExpr(v *Variable) (Value, *LoxError) {
+	return interp.lookUpVariable(v, v.Name)
+}
+
+// lookUpVariable returns the value of the variable name that expr refers to.
+func (interp *Interpreter) lookUpVariable(expr Expr, name Token) (Value, *LoxError) {
+	var val Value
 	var err *LoxError
 
-	if d, ok := interp.locals[v]; ok {
-		val, err = interp.env.GetAt(v.Name, d)
+	if l, ok := interp.locals[expr]; ok {
+		val = interp.env.GetAt(l.depth, l.slot)
+	} else {
+		val, err = interp.globEnv.Get(name)
 		if err != nil {
-			return nil, err
+			return NilValue, err
 		}
-	} else {
-		val, err = interp.globEnv.Get(v.Name)
 	}
 
-	if val == nil {
-		return nil,
-			&LoxError{File: v.Name.File,
-				Line:   v.Name.Line,
-				Col:    v.Name.Col,
-				Number: UnassignedVariable,
-				Msg:    fmt.Sprintf("Usage of unassigned variable %s", v.Name.Lexeme)}
+	if err = CheckAssigned(name, val); err != nil {
+		return NilValue, err
 	}
 
 	return val, nil
 }
 
-func (interp *Interpreter) AcceptLogicalExpr(l *Logical) (interface{}, *LoxError) {
-	left, err := interp.evaluate(l.Left)
-	if err != nil {
-		return nil, err
-	}
+// assignVariable sets the variable name that expr refers to.
+func (interp *Interpreter) assignVariable(expr Expr, name Token, val Value) *LoxError {
+	if l, ok := interp.locals[expr]; ok {
+		interp.env.AssignAt(l.depth, l.slot, val)
 
-	if interp.isTruthy(left) && l.Operator.Type == OR {
-		return left, nil
-	} else if !interp.isTruthy(left) && l.Operator.Type == AND {
-		return left, nil
+		return nil
 	}
 
-	return interp.evaluate(l.Right)
+	return interp.globEnv.Assign(name, val)
 }
 
-func (interp *Interpreter) Resolve(expr Expr, depth int) {
-	interp.locals[expr] = depth
-}
-
-func (interp *Interpreter) checkNumberOperand(op Token, r interface{}) *LoxError {
-	if _, ok := r.(float64); !ok {
-		return &LoxError{
-			Number: UnexpectedChar, File: op.File, Line: op.Line, Col: op.Col,
-			Msg: fmt.Sprintf("Expected a number but found `%s`.", op.Lexeme),
-		}
+func (interp *Interpreter) AcceptLogicalExpr(l *Logical) (Value, *LoxError) {
+	left, err := interp.evaluate(l.Left)
+	if err != nil {
+		return NilValue, err
 	}
 
-	return nil
-}
+	if (left.IsTruthy() && l.Operator.Type == OR) || (!left.IsTruthy() && l.Operator.Type == AND) {
+		if interp.coverage != nil {
+			interp.coverage.branch(l, 0)
+		}
 
-func (interp *Interpreter) checkNumberOperands(op Token, l, r interface{}) *LoxError {
-	_, ok1 := l.(float64)
-	_, ok2 := r.(float64)
+		return left, nil
+	}
 
-	if !ok1 || !ok2 {
-		return &LoxError{
-			Number: UnexpectedChar, File: op.File, Line: op.Line, Col: op.Col,
-			Msg: "Operands must be numbers.",
-		}
+	if interp.coverage != nil {
+		interp.coverage.branch(l, 1)
 	}
 
-	return nil
+	return interp.evaluate(l.Right)
 }
 
-func (interp *Interpreter) isTruthy(v interface{}) bool {
-	if _, ok := v.(Nil); ok {
-		return false
+func (interp *Interpreter) AcceptGetExpr(g *Get) (Value, *LoxError) {
+	obj, err := interp.evaluate(g.Object)
+	if err != nil {
+		return NilValue, err
 	}
 
-	switch v := v.(type) {
-	case bool:
-		return v
-	default:
-		return true
-	}
+	return GetProperty(g.Name, obj)
 }
 
-func (interp *Interpreter) isEqual(lv, rv interface{}) bool {
-	if lv == nil && rv == nil {
-		return true
-	}
+func (interp *Interpreter) AcceptLambdaExpr(l *Lambda) (Value, *LoxError) {
+	return ObjectValue(NewLoxFunction(l.Decl, interp.env, interp)), nil
+}
 
-	if lv == nil || rv == nil {
-		return false
-	}
+// Resolve records that expr refers to the local variable in the given slot of
+// the scope depth levels up from the current one.
+func (interp *Interpreter) Resolve(expr Expr, depth, slot int) {
+	interp.locals[expr] = local{depth: depth, slot: slot}
+}
 
-	return lv == rv
+// resolveTailCall records that r returns a call in tail position.
+func (interp *Interpreter) resolveTailCall(r *Return) {
+	interp.tailCalls[r] = true
 }
 
-func (interp *Interpreter) evaluate(expr Expr) (interface{}, *LoxError) {
+func (interp *Interpreter) evaluate(expr Expr) (Value, *LoxError) {
 	return expr.Accept(interp)
 }
 
-func (interp *Interpreter) execute(stmt Stmt) (interface{}, *LoxError) {
+func (interp *Interpreter) execute(stmt Stmt) (Value, *LoxError) {
+	if interp.profiler != nil {
+		interp.profiler.enterStmt(stmt)
+		defer interp.profiler.leaveStmt()
+	}
+
+	if interp.coverage != nil {
+		interp.coverage.hitStmt(stmt)
+	}
+
 	return stmt.Accept(interp)
 }
@@ -1,4 +0,0 @@
-package golox
-
-type Nil struct {
-}
\ No newline at end of file
@@ -0,0 +1,183 @@
+package golox
+
+import "io"
+
+// Optimizer rewrites a resolved program before it is interpreted. It folds
+// operators whose operands are literals into literals and removes the
+// branches and loops whose conditions are literals that never let them run.
+//
+// Folding evaluates the operator with the Interpreter itself, so the folded
+// value is exactly the one that would have been computed at run time. An
+// operator that fails is left in place to report its error at run time.
+//
+// Only subtrees without variables are replaced, so the resolution of the
+// remaining Variable and Assign nodes stays valid.
+type Optimizer struct {
+	folder *Interpreter
+}
+
+func NewOptimizer() *Optimizer {
+	folder := NewInterpreter()
+	folder.SetOutput(io.Discard)
+
+	return &Optimizer{folder: folder}
+}
+
+// Optimize rewrites the nodes of stmts in place and returns the statements
+// that remain.
+func (o *Optimizer) Optimize(stmts []Stmt) []Stmt {
+	res := make([]Stmt, 0, len(stmts))
+
+	for _, s := range stmts {
+		if s = o.optimizeStmt(s); s != nil {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// optimizeStmt returns the statement to run instead of s, or nil if s can be
+// removed.
+func (o *Optimizer) optimizeStmt(s Stmt) Stmt {
+	switch st := s.(type) {
+	case *Block:
+		st.Stmts = o.Optimize(st.Stmts)
+	case *Expression:
+		st.Expr = o.optimizeExpr(st.Expr)
+	case *Print:
+		st.Expr = o.optimizeExpr(st.Expr)
+	case *Var:
+		if st.Initializer != nil {
+			st.Initializer = o.optimizeExpr(st.Initializer)
+		}
+	case *Func:
+		st.Body = o.Optimize(st.Body)
+	case *If:
+		st.Condition = o.optimizeExpr(st.Condition)
+		st.Body = o.optimizeBody(st.Body)
+		if st.ElseBody != nil {
+			st.ElseBody = o.optimizeStmt(st.ElseBody)
+		}
+
+		if l, ok := st.Condition.(*Literal); ok {
+			if l.Value.IsTruthy() {
+				return st.Body
+			}
+
+			return st.ElseBody
+		}
+	case *While:
+		st.Condition = o.optimizeExpr(st.Condition)
+		st.Body = o.optimizeBody(st.Body)
+
+		if l, ok := st.Condition.(*Literal); ok && !l.Value.IsTruthy() {
+			return nil
+		}
+	case *Return:
+		if st.Value != nil {
+			st.Value = o.optimizeExpr(st.Value)
+		}
+	case *Throw:
+		st.Value = o.optimizeExpr(st.Value)
+	case *Try:
+		st.Body = o.Optimize(st.Body)
+		if st.CatchBody != nil {
+			st.CatchBody = o.Optimize(st.CatchBody)
+		}
+		if st.FinallyBody != nil {
+			st.FinallyBody = o.Optimize(st.FinallyBody)
+		}
+	}
+
+	return s
+}
+
+// optimizeBody optimizes a statement that cannot be removed because it is
+// the body of another one, replacing it with an empty block if it is dead.
+func (o *Optimizer) optimizeBody(s Stmt) Stmt {
+	if res := o.optimizeStmt(s); res != nil {
+		return res
+	}
+
+	return &Block{Stmts: []Stmt{}}
+}
+
+func (o *Optimizer) optimizeExpr(e Expr) Expr {
+	switch ex := e.(type) {
+	case *Assign:
+		ex.Value = o.optimizeExpr(ex.Value)
+	case *Binary:
+		ex.Left = o.optimizeExpr(ex.Left)
+		ex.Right = o.optimizeExpr(ex.Right)
+
+		if isLiteral(ex.Left) && isLiteral(ex.Right) {
+			return o.fold(ex)
+		}
+	case *Grouping:
+		ex.Expr = o.optimizeExpr(ex.Expr)
+
+		if isLiteral(ex.Expr) {
+			return ex.Expr
+		}
+	case *Unary:
+		ex.Right = o.optimizeExpr(ex.Right)
+
+		if isLiteral(ex.Right) {
+			return o.fold(ex)
+		}
+	case *Call:
+		ex.Callee = o.optimizeExpr(ex.Callee)
+		for i, arg := range ex.Args {
+			ex.Args[i] = o.optimizeExpr(arg)
+		}
+	case *Conditional:
+		ex.Condition = o.optimizeExpr(ex.Condition)
+		ex.Then = o.optimizeExpr(ex.Then)
+		ex.Else = o.optimizeExpr(ex.Else)
+
+		if l, ok := ex.Condition.(*Literal); ok {
+			if l.Value.IsTruthy() {
+				return ex.Then
+			}
+
+			return ex.Else
+		}
+	case *CompoundAssign:
+		ex.Value = o.optimizeExpr(ex.Value)
+	case *Get:
+		ex.Object = o.optimizeExpr(ex.Object)
+	case *Lambda:
+		ex.Decl.Body = o.Optimize(ex.Decl.Body)
+	case *Logical:
+		ex.Left = o.optimizeExpr(ex.Left)
+		ex.Right = o.optimizeExpr(ex.Right)
+
+		if l, ok := ex.Left.(*Literal); ok {
+			if l.Value.IsTruthy() == (ex.Operator.Type == OR) {
+				return ex.Left
+			}
+
+			return ex.Right
+		}
+	}
+
+	return e
+}
+
+// fold evaluates e, whose operands are literals, or returns it unchanged if
+// that fails.
+func (o *Optimizer) fold(e Expr) Expr {
+	v, err := o.folder.evaluate(e)
+	if err != nil {
+		return e
+	}
+
+	return &Literal{Value: v}
+}
+
+func isLiteral(e Expr) bool {
+	_, ok := e.(*Literal)
+
+	return ok
+}
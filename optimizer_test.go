@@ -0,0 +1,43 @@
+package golox_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+var optimizerTestData = map[string]string{
+	"var a = 2 * 60 * 60;":                "(var a = 7200)",
+	"print -(1 + 2) * a;":                 "(print (* -3 a))",
+	"print \"${1 + 1} apples\";":          "(print \"2 apples\")",
+	"print \"a\" - 1;":                    "(print (- \"a\" 1))",
+	"print a + 1 + 2;":                    "(print (+ (+ a 1) 2))",
+	"print true and a;":                   "(print a)",
+	"print nil and a;":                    "(print nil)",
+	"if (1 > 2) print a; else print 1;":   "(print 1)",
+	"if (!false) { print a; }":            "(block (print a))",
+	"if (false) print a; print 2;":        "(print 2)",
+	"while (nil) print a;":                "",
+	"while (a) if (false) print a;":       "(while a (block))",
+	"fun f() { if (true) return 1 + 1; }": "(fun f() (return 2))",
+}
+
+func TestOptimizer(t *testing.T) {
+	ap := &golox.AstPrinter{}
+	for src, expected := range optimizerTestData {
+		stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(src))).Parse()
+		if err != nil {
+			t.Fatalf("Failed on %q. Got error on p.Parse(): %v", src, err)
+		}
+
+		actual := make([]string, 0)
+		for _, stmt := range golox.NewOptimizer().Optimize(stmts) {
+			actual = append(actual, ap.PrintStmt(stmt))
+		}
+
+		if strings.Join(actual, " ") != expected {
+			t.Fatalf("Failed on %q. Expected: %s, got: %s", src, expected, strings.Join(actual, " "))
+		}
+	}
+}
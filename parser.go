@@ -2,9 +2,13 @@ package golox
 
 import (
 	"fmt"
+	"math/big"
 )
 
 type Parser struct {
+	// Tokens are pulled from the scanner as they are needed. When parsing
+	// a pre-scanned slice of tokens, the scanner is nil.
+	scanner *Scanner
 	tokens  []Token
 	current int
 }
@@ -13,24 +17,83 @@ func NewParser(tokens []Token) *Parser {
 	return &Parser{tokens: tokens, current: 0}
 }
 
+// NewStreamParser creates a parser that consumes the tokens of s lazily.
+func NewStreamParser(s *Scanner) *Parser {
+	return &Parser{scanner: s, tokens: make([]Token, 0), current: 0}
+}
+
 func (p *Parser) Parse() ([]Stmt, *LoxError) {
-	p.current = 0
+	if p.scanner == nil {
+		p.current = 0
+	}
+
 	stmts := make([]Stmt, 0)
 
 	for !p.isAtEnd() {
 		stmt, err := p.parseDeclaration()
+		if serr := p.scannerError(); serr != nil {
+			return nil, serr
+		}
+
 		if err != nil {
 			return nil, err
 		}
 
 		stmts = append(stmts, stmt)
+		p.discard()
+	}
+
+	if serr := p.scannerError(); serr != nil {
+		return nil, serr
 	}
 
 	return stmts, nil
 }
 
+// scannerError reports the error that made the scanner stop early. Such an
+// error takes precedence over the parse errors it causes.
+func (p *Parser) scannerError() *LoxError {
+	if p.scanner == nil || p.scanner.Err() == nil {
+		return nil
+	}
+
+	if lerr, ok := p.scanner.Err().(*LoxError); ok {
+		return lerr
+	}
+
+	return &LoxError{Number: UnexpectedChar, Msg: p.scanner.Err().Error()}
+}
+
+// fill makes sure the current token is buffered unless the scanner is exhausted.
+func (p *Parser) fill() {
+	p.fillTo(p.current)
+}
+
+// fillTo makes sure the token at index i is buffered unless the scanner is
+// exhausted.
+func (p *Parser) fillTo(i int) {
+	if p.scanner == nil {
+		return
+	}
+
+	for i >= len(p.tokens) && (len(p.tokens) == 0 || p.tokens[len(p.tokens)-1].Type != EOF) {
+		p.tokens = append(p.tokens, p.scanner.Next())
+	}
+}
+
+// discard drops the tokens that have already been parsed.
+func (p *Parser) discard() {
+	if p.scanner == nil {
+		return
+	}
+
+	p.tokens = append(p.tokens[:0], p.tokens[p.current:]...)
+	p.current = 0
+}
+
 func (p *Parser) parseDeclaration() (Stmt, *LoxError) {
-	if p.peek(FUN) {
+	// A `fun` not followed by a name starts an anonymous function.
+	if p.peek(FUN) && p.peekAt(1, IDENTIFIER) {
 		s, err := p.parseFunDeclaration()
 		if err != nil {
 			return nil, err
@@ -66,7 +129,7 @@ func (p *Parser) parseFunDeclaration() (Stmt, *LoxError) {
 		return nil, err
 	}
 
-	params, err := p.parseParams()
+	params, types, err := p.parseParams()
 	if err != nil {
 		return nil, err
 	}
@@ -76,40 +139,117 @@ func (p *Parser) parseFunDeclaration() (Stmt, *LoxError) {
 		return nil, err
 	}
 
+	ret, err := p.parseOptionalType()
+	if err != nil {
+		return nil, err
+	}
+
 	b, err := p.parseBlock()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Func{Name: *name, Params: params, Body: b}, nil
+	return &Func{Name: *name, Params: params, ParamTypes: types, ReturnType: ret, Body: b}, nil
 }
 
-func (p *Parser) parseParams() ([]Token, *LoxError) {
+// parseParams parses the parameters of a function and their type annotations,
+// which are nil for parameters without one.
+func (p *Parser) parseParams() ([]Token, []*TypeExpr, *LoxError) {
 	res := make([]Token, 0)
+	types := make([]*TypeExpr, 0)
 
 	firstArg := true
 
 	for !p.peek(RIGHT_PAREN) {
 		if !firstArg {
 			if _, err := p.consume(COMMA); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
+		if p.isAtEnd() {
+			return nil, nil, p.genEOFError("parameter name")
+		}
+
 		t := p.getNextToken()
 
 		if len(res) >= 255 {
-			return nil, genError(t, ParamLimitExceeded, "Can't have more than 255 parameters.")
+			return nil, nil, genError(t, ParamLimitExceeded, "Can't have more than 255 parameters.")
 		}
 
 		if t.Type != IDENTIFIER {
-			return nil, genError(t, InvalidParamName, fmt.Sprintf("Expect parameter name, got %s.", t.Lexeme))
+			return nil, nil, genError(t, InvalidParamName, fmt.Sprintf("Expect parameter name, got %s.", t.Lexeme))
+		}
+
+		typ, err := p.parseOptionalType()
+		if err != nil {
+			return nil, nil, err
 		}
 
 		res = append(res, t)
+		types = append(types, typ)
 
 		firstArg = false
 	}
 
+	return res, types, nil
+}
+
+// parseOptionalType parses the type annotation introduced by a colon, if
+// there is one.
+func (p *Parser) parseOptionalType() (*TypeExpr, *LoxError) {
+	if !p.peek(COLON) {
+		return nil, nil
+	}
+
+	p.getNextToken()
+
+	return p.parseType()
+}
+
+func (p *Parser) parseType() (*TypeExpr, *LoxError) {
+	if p.isAtEnd() {
+		return nil, p.genEOFError("type")
+	}
+
+	t := p.getNextToken()
+	if t.Type != IDENTIFIER && t.Type != NIL && t.Type != FUN {
+		return nil, genError(t, InvalidType, fmt.Sprintf("Expect type, got %s.", t.Lexeme))
+	}
+
+	res := &TypeExpr{Name: t}
+	if t.Type != FUN || !p.peek(LEFT_PAREN) {
+		return res, nil
+	}
+
+	p.getNextToken()
+	res.Params = make([]*TypeExpr, 0)
+
+	for !p.peek(RIGHT_PAREN) {
+		if len(res.Params) > 0 {
+			if _, err := p.consume(COMMA); err != nil {
+				return nil, err
+			}
+		}
+
+		param, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+
+		res.Params = append(res.Params, param)
+	}
+
+	if _, err := p.consume(RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+
+	ret, err := p.parseOptionalType()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Return = ret
+
 	return res, nil
 }
 
@@ -124,6 +264,11 @@ func (p *Parser) parseVarDeclaration() (Stmt, *LoxError) {
 		return nil, err2
 	}
 
+	typ, err := p.parseOptionalType()
+	if err != nil {
+		return nil, err
+	}
+
 	var initializer Expr
 
 	if p.peek(EQUAL) {
@@ -132,7 +277,7 @@ func (p *Parser) parseVarDeclaration() (Stmt, *LoxError) {
 			return nil, err3
 		}
 
-		init, err4 := p.parseExpression()
+		init, err4 := p.parseAssignment()
 		if err4 != nil {
 			return nil, err4
 		}
@@ -145,7 +290,7 @@ func (p *Parser) parseVarDeclaration() (Stmt, *LoxError) {
 		return nil, err3
 	}
 
-	return &Var{Name: *name, Initializer: initializer}, nil
+	return &Var{Name: *name, Type: typ, Initializer: initializer}, nil
 }
 
 func (p *Parser) parseStmt() (Stmt, *LoxError) {
@@ -166,6 +311,10 @@ func (p *Parser) parseStmt() (Stmt, *LoxError) {
 		return p.parseForStmt()
 	} else if p.peek(RETURN) {
 		return p.parseReturnStmt()
+	} else if p.peek(THROW) {
+		return p.parseThrowStmt()
+	} else if p.peek(TRY) {
+		return p.parseTryStmt()
 	}
 
 	return p.parseExprStmt()
@@ -192,8 +341,85 @@ func (p *Parser) parseReturnStmt() (Stmt, *LoxError) {
 	return &Return{Keyword: *ret, Value: val}, nil
 }
 
+func (p *Parser) parseThrowStmt() (Stmt, *LoxError) {
+	keyword, err := p.consume(THROW)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = p.consume(SEMICOLON); err != nil {
+		return nil, err
+	}
+
+	return &Throw{Keyword: *keyword, Value: val}, nil
+}
+
+// parseTryStmt parses a try statement, which needs a catch clause, a finally
+// clause or both:
+//
+//	try { ... } catch (e) { ... } finally { ... }
+func (p *Parser) parseTryStmt() (Stmt, *LoxError) {
+	keyword, err := p.consume(TRY)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Try{Keyword: *keyword, Body: body}
+
+	if p.peek(CATCH) {
+		p.getNextToken()
+
+		if _, err = p.consume(LEFT_PAREN); err != nil {
+			return nil, err
+		}
+
+		name, err := p.consume(IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = p.consume(RIGHT_PAREN); err != nil {
+			return nil, err
+		}
+
+		if res.CatchBody, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+
+		res.CatchName = *name
+	}
+
+	if p.peek(FINALLY) {
+		p.getNextToken()
+
+		if res.FinallyBody, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	if res.CatchBody == nil && res.FinallyBody == nil {
+		if p.isAtEnd() {
+			return nil, p.genEOFError("`catch` or `finally`")
+		}
+
+		return nil, genError(*keyword, InvalidTry, "Expect 'catch' or 'finally' after try block.")
+	}
+
+	return res, nil
+}
+
 func (p *Parser) parsePrintStmt() (Stmt, *LoxError) {
-	_, err := p.consume(PRINT)
+	keyword, err := p.consume(PRINT)
 	if err != nil {
 		return nil, err
 	}
@@ -208,11 +434,12 @@ func (p *Parser) parsePrintStmt() (Stmt, *LoxError) {
 		return nil, err3
 	}
 
-	return &Print{Expr: expr}, nil
+	return &Print{Keyword: *keyword, Expr: expr}, nil
 }
 
 func (p *Parser) parseIfStmt() (Stmt, *LoxError) {
-	if _, err := p.consume(IF); err != nil {
+	keyword, err := p.consume(IF)
+	if err != nil {
 		return nil, err
 	}
 
@@ -248,11 +475,12 @@ func (p *Parser) parseIfStmt() (Stmt, *LoxError) {
 		elseBody = stmt
 	}
 
-	return &If{Condition: expr, Body: body, ElseBody: elseBody}, nil
+	return &If{Keyword: *keyword, Condition: expr, Body: body, ElseBody: elseBody}, nil
 }
 
 func (p *Parser) parseWhileStmt() (Stmt, *LoxError) {
-	if _, err := p.consume(WHILE); err != nil {
+	keyword, err := p.consume(WHILE)
+	if err != nil {
 		return nil, err
 	}
 
@@ -274,11 +502,12 @@ func (p *Parser) parseWhileStmt() (Stmt, *LoxError) {
 		return nil, err2
 	}
 
-	return &While{Condition: expr, Body: body}, nil
+	return &While{Keyword: *keyword, Condition: expr, Body: body}, nil
 }
 
 func (p *Parser) parseForStmt() (Stmt, *LoxError) {
-	if _, err := p.consume(FOR); err != nil {
+	keyword, err := p.consume(FOR)
+	if err != nil {
 		return nil, err
 	}
 
@@ -311,7 +540,7 @@ func (p *Parser) parseForStmt() (Stmt, *LoxError) {
 	}
 
 	if cond != nil {
-		body = &While{Condition: cond, Body: body}
+		body = &While{Keyword: *keyword, Condition: cond, Body: body}
 	}
 
 	if init != nil {
@@ -420,11 +649,33 @@ func (p *Parser) parseBlock() ([]Stmt, *LoxError) {
 }
 
 func (p *Parser) parseExpression() (Expr, *LoxError) {
-	return p.parseAssignment()
+	return p.parseComma()
+}
+
+// parseComma parses the comma operator, which evaluates its operands from left
+// to right and results in the value of the right one.
+func (p *Parser) parseComma() (Expr, *LoxError) {
+	expr, err := p.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek(COMMA) {
+		t := p.getNextToken()
+
+		right, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = &Binary{Left: expr, Operator: t, Right: right}
+	}
+
+	return expr, nil
 }
 
 func (p *Parser) parseAssignment() (Expr, *LoxError) {
-	expr, err := p.parseOr()
+	expr, err := p.parseConditional()
 	if err != nil {
 		return nil, err
 	}
@@ -448,9 +699,54 @@ func (p *Parser) parseAssignment() (Expr, *LoxError) {
 		return &Assign{Name: v.Name, Value: assignment}, nil
 	}
 
+	if p.peek(PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL, PERCENT_EQUAL) {
+		op := p.getNextToken()
+
+		v, ok := expr.(*Variable)
+		if !ok {
+			return nil, genError(op, InvalidAssignment, "Invalid assignment target.")
+		}
+
+		value, err2 := p.parseAssignment()
+		if err2 != nil {
+			return nil, err2
+		}
+
+		return &CompoundAssign{Name: v.Name, Operator: op, Value: value}, nil
+	}
+
 	return expr, nil
 }
 
+func (p *Parser) parseConditional() (Expr, *LoxError) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.peek(QUESTION) {
+		return cond, nil
+	}
+
+	question := p.getNextToken()
+
+	then, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = p.consume(COLON); err != nil {
+		return nil, err
+	}
+
+	els, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conditional{Condition: cond, Question: question, Then: then, Else: els}, nil
+}
+
 func (p *Parser) parseOr() (Expr, *LoxError) {
 	left, err := p.parseAnd()
 	if err != nil {
@@ -563,7 +859,7 @@ func (p *Parser) parseFactor() (Expr, *LoxError) {
 		return nil, err
 	}
 
-	for p.peek(STAR, SLASH) {
+	for p.peek(STAR, SLASH, TILDE_SLASH, PERCENT) {
 		t := p.getNextToken()
 
 		right, err := p.parseUnary()
@@ -589,7 +885,38 @@ func (p *Parser) parseUnary() (Expr, *LoxError) {
 		return &Unary{Operator: t, Right: right}, nil
 	}
 
-	return p.parseCall()
+	if p.peek(PLUS_PLUS, MINUS_MINUS) {
+		t := p.getNextToken()
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return p.increment(t, right, false)
+	}
+
+	expr, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek(PLUS_PLUS, MINUS_MINUS) {
+		return p.increment(p.getNextToken(), expr, true)
+	}
+
+	return expr, nil
+}
+
+// increment creates the increment or decrement of target by op, which must be
+// a variable.
+func (p *Parser) increment(op Token, target Expr, postfix bool) (Expr, *LoxError) {
+	v, ok := target.(*Variable)
+	if !ok {
+		return nil, genError(op, InvalidAssignment, "Invalid increment target.")
+	}
+
+	return &Increment{Name: v.Name, Operator: op, Postfix: postfix}, nil
 }
 
 func (p *Parser) parseCall() (Expr, *LoxError) {
@@ -601,6 +928,19 @@ func (p *Parser) parseCall() (Expr, *LoxError) {
 	res := pr
 
 	for true {
+		if p.peek(DOT) {
+			p.getNextToken()
+
+			name, err2 := p.consume(IDENTIFIER)
+			if err2 != nil {
+				return nil, err2
+			}
+
+			res = &Get{Object: res, Name: *name}
+
+			continue
+		}
+
 		if !p.peek(LEFT_PAREN) {
 			break
 		}
@@ -642,7 +982,9 @@ func (p *Parser) parseArguments() ([]Expr, *LoxError) {
 			return nil, genError(p.getNextToken(), ArgumentLimitExceeded, "Can't have more than 255 arguments.")
 		}
 
-		expr, err := p.parseExpression()
+		// Arguments are separated by commas, so they cannot be comma expressions
+		// unless they are parenthesized.
+		expr, err := p.parseAssignment()
 		if err != nil {
 			return nil, err
 		}
@@ -656,22 +998,42 @@ func (p *Parser) parseArguments() ([]Expr, *LoxError) {
 }
 
 func (p *Parser) parsePrimary() (Expr, *LoxError) {
+	if p.isAtEnd() {
+		return nil, p.genEOFError("an expression")
+	}
+
 	t := p.getNextToken()
 
-	if t.Type == NUMBER || t.Type == STRING {
-		return &Literal{Value: t.Literal}, nil
+	if t.Type == NUMBER {
+		return &Literal{Value: numberLiteral(t.Literal)}, nil
+	}
+
+	if t.Type == STRING {
+		return &Literal{Value: StringValue(t.Literal.(string))}, nil
+	}
+
+	if t.Type == INTERPOLATION {
+		return p.parseInterpolation(t)
 	}
 
 	if t.Type == TRUE {
-		return &Literal{Value: true}, nil
+		return &Literal{Value: BoolValue(true)}, nil
 	}
 
 	if t.Type == FALSE {
-		return &Literal{Value: false}, nil
+		return &Literal{Value: BoolValue(false)}, nil
 	}
 
 	if t.Type == NIL {
-		return &Literal{Value: Nil{}}, nil
+		return &Literal{Value: NilValue}, nil
+	}
+
+	if t.Type == FUN {
+		return p.parseLambda(t)
+	}
+
+	if t.Type == LEFT_PAREN && p.isArrowFunction() {
+		return p.parseArrowFunction(t)
 	}
 
 	if t.Type == IDENTIFIER {
@@ -695,6 +1057,187 @@ func (p *Parser) parsePrimary() (Expr, *LoxError) {
 	return nil, &LoxError{Number: UnexpectedChar, File: t.File, Line: t.Line, Col: t.Col, Msg: fmt.Sprintf("Expected one of (number, string, `true`, `false`, `nil`, identifier, `(`}) but found `%s`.", t.Lexeme)}
 }
 
+// parseLambda parses an anonymous function after its `fun` keyword:
+//
+//	fun (a, b) { return a + b; }
+func (p *Parser) parseLambda(keyword Token) (Expr, *LoxError) {
+	if _, err := p.consume(LEFT_PAREN); err != nil {
+		return nil, err
+	}
+
+	params, types, err := p.parseParams()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = p.consume(RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+
+	ret, err := p.parseOptionalType()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lambda{Decl: &Func{Name: lambdaName(keyword), Params: params, ParamTypes: types, ReturnType: ret, Body: body}}, nil
+}
+
+// isArrowFunction reports whether the tokens following the current `(` are
+// the parameters of an arrow function, possibly annotated with types, up to
+// its `=>`.
+func (p *Parser) isArrowFunction() bool {
+	depth := 1
+
+	// Only the tokens of parameters and type annotations are skipped, so that
+	// telling a grouping from an arrow function stays cheap.
+	for i := 0; ; i++ {
+		switch p.tokenTypeAt(i) {
+		case LEFT_PAREN:
+			depth++
+		case RIGHT_PAREN:
+			depth--
+			if depth == 0 {
+				return p.isArrowAfterParams(i + 1)
+			}
+		case IDENTIFIER, COMMA, COLON, NIL, FUN:
+		default:
+			return false
+		}
+	}
+}
+
+// isArrowAfterParams reports whether the tokens from the i-th one after the
+// current one are the `=>` of an arrow function, possibly preceded by its
+// return type.
+func (p *Parser) isArrowAfterParams(i int) bool {
+	switch p.tokenTypeAt(i) {
+	case ARROW:
+		return true
+	case COLON:
+	default:
+		return false
+	}
+
+	depth := 0
+
+	for i++; ; i++ {
+		switch p.tokenTypeAt(i) {
+		case LEFT_PAREN:
+			depth++
+		case RIGHT_PAREN:
+			depth--
+			if depth < 0 {
+				return false
+			}
+		case COMMA:
+			if depth == 0 {
+				return false
+			}
+		case IDENTIFIER, COLON, NIL, FUN:
+		case ARROW:
+			return depth == 0
+		default:
+			return false
+		}
+	}
+}
+
+// parseArrowFunction parses an arrow function after its `(`. Its body is
+// either a block or an expression whose value is returned:
+//
+//	(a) => a * 2
+//	(a) => { print a; }
+func (p *Parser) parseArrowFunction(paren Token) (Expr, *LoxError) {
+	params, types, err := p.parseParams()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = p.consume(RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+
+	ret, err := p.parseOptionalType()
+	if err != nil {
+		return nil, err
+	}
+
+	arrow, err := p.consume(ARROW)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []Stmt
+	if p.peek(LEFT_BRACE) {
+		if body, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+	} else {
+		value, err2 := p.parseAssignment()
+		if err2 != nil {
+			return nil, err2
+		}
+
+		body = []Stmt{&Return{Keyword: *arrow, Value: value}}
+	}
+
+	return &Lambda{Decl: &Func{Name: lambdaName(paren), Params: params, ParamTypes: types, ReturnType: ret, Body: body}}, nil
+}
+
+// lambdaName is the name given to anonymous functions, positioned at the
+// token starting them.
+func lambdaName(start Token) Token {
+	start.Lexeme = "lambda"
+
+	return start
+}
+
+// numberLiteral converts the literal of a NUMBER token, a float64, an int64 or
+// a *big.Int, to a Value.
+func numberLiteral(lit interface{}) Value {
+	switch n := lit.(type) {
+	case int64:
+		return IntValue(n)
+	case *big.Int:
+		return BigIntValue(n)
+	}
+
+	return NumberValue(lit.(float64))
+}
+
+// parseInterpolation lowers an interpolated string into a concatenation of
+// its literal parts and its stringified expressions.
+func (p *Parser) parseInterpolation(start Token) (Expr, *LoxError) {
+	plus := Token{Type: PLUS, Lexeme: "+", File: start.File, Line: start.Line, Col: start.Col}
+
+	var res Expr = &Literal{Value: StringValue(start.Literal.(string))}
+
+	for t := start; t.Type == INTERPOLATION; {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		// The INTERPOLATION operator stringifies its operand.
+		res = &Binary{Left: res, Operator: plus, Right: &Unary{Operator: t, Right: expr}}
+
+		if p.isAtEnd() || !p.peek(INTERPOLATION, STRING) {
+			_, err = p.consume(STRING)
+			return nil, err
+		}
+
+		t = p.getNextToken()
+		res = &Binary{Left: res, Operator: plus, Right: &Literal{Value: StringValue(t.Literal.(string))}}
+	}
+
+	return res, nil
+}
+
 func (p *Parser) sync() {
 	for !p.isAtEnd() {
 		t := p.getNextToken()
@@ -702,7 +1245,7 @@ func (p *Parser) sync() {
 			return
 		}
 
-		if p.peek(CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN) {
+		if p.peek(CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN, THROW, TRY) {
 			return
 		}
 	}
@@ -710,8 +1253,7 @@ func (p *Parser) sync() {
 
 func (p *Parser) consume(tt TokenType) (*Token, *LoxError) {
 	if p.isAtEnd() {
-		lt := p.tokens[len(p.tokens)-1]
-		return nil, &LoxError{Number: UnfinishedExpression, File: lt.File, Line: lt.Line, Col: lt.Col, Msg: fmt.Sprintf("Unfinished expression. Expected `%s` but found EOF.", tt)}
+		return nil, p.genEOFError(fmt.Sprintf("`%s`", tt))
 	}
 
 	t := p.getNextToken()
@@ -723,7 +1265,14 @@ func (p *Parser) consume(tt TokenType) (*Token, *LoxError) {
 	return &t, nil
 }
 
+func (p *Parser) genEOFError(expected string) *LoxError {
+	lt := p.tokens[len(p.tokens)-1]
+
+	return &LoxError{Number: UnexpectedEOF, File: lt.File, Line: lt.Line, Col: lt.Col, Msg: fmt.Sprintf("Unexpected end of input. Expected %s.", expected)}
+}
+
 func (p *Parser) getNextToken() Token {
+	p.fill()
 	p.current += 1
 
 	return p.tokens[p.current-1]
@@ -743,6 +1292,28 @@ func (p *Parser) peek(ts ...TokenType) bool {
 	return false
 }
 
+// peekAt reports whether the token n positions after the current one has the
+// type t.
+func (p *Parser) peekAt(n int, t TokenType) bool {
+	p.fillTo(p.current + n)
+
+	return p.current+n < len(p.tokens) && p.tokens[p.current+n].Type == t
+}
+
+// tokenTypeAt returns the type of the token n positions after the current one,
+// or EOF if the input ends before it.
+func (p *Parser) tokenTypeAt(n int) TokenType {
+	p.fillTo(p.current + n)
+
+	if p.current+n >= len(p.tokens) {
+		return EOF
+	}
+
+	return p.tokens[p.current+n].Type
+}
+
 func (p *Parser) isAtEnd() bool {
+	p.fill()
+
 	return p.current >= len(p.tokens) || p.tokens[p.current].Type == EOF
 }
@@ -1,7 +1,9 @@
 package golox_test
 
 import (
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/agayev169/golox"
 )
@@ -20,11 +22,12 @@ var parserTestData = map[string]parserTestDto{
 	"simple": {
 		Tokens: []golox.Token{
 			{Type: golox.MINUS, Lexeme: "-"},
-			{Type: golox.NUMBER, Lexeme: "123", Literal: 123},
+			{Type: golox.NUMBER, Lexeme: "123", Literal: 123.0},
 			{Type: golox.STAR, Lexeme: "*"},
 			{Type: golox.LEFT_PAREN, Lexeme: "("},
 			{Type: golox.NUMBER, Lexeme: "45.67", Literal: 45.67},
 			{Type: golox.RIGHT_PAREN, Lexeme: ")"},
+			{Type: golox.SEMICOLON, Lexeme: ";"},
 			{Type: golox.EOF},
 		},
 		Expected: parserOutputDto{
@@ -35,7 +38,7 @@ var parserTestData = map[string]parserTestDto{
 						Lexeme: "-",
 					},
 					Right: &golox.Literal{
-						Value: 123,
+						Value: golox.NumberValue(123),
 					},
 				},
 				Operator: golox.Token{
@@ -44,7 +47,7 @@ var parserTestData = map[string]parserTestDto{
 				},
 				Right: &golox.Grouping{
 					Expr: &golox.Literal{
-						Value: 45.67,
+						Value: golox.NumberValue(45.67),
 					},
 				},
 			},
@@ -53,13 +56,13 @@ var parserTestData = map[string]parserTestDto{
 	},
 	"errorful": {
 		Tokens: []golox.Token{
-			{Type: golox.NUMBER, Lexeme: "1", Literal: 1},
+			{Type: golox.NUMBER, Lexeme: "1", Literal: 1.0},
 			{Type: golox.PLUS, Lexeme: "+"},
 			{Type: golox.EOF},
 		},
 		Expected: parserOutputDto{
 			Expression: nil,
-			Error:      &golox.LoxError{Number: golox.UnexpectedChar},
+			Error:      &golox.LoxError{Number: golox.UnexpectedEOF},
 		},
 	},
 }
@@ -72,8 +75,49 @@ func TestParser(t *testing.T) {
 			t.Fatalf("Failed on test %s. Got error on p.Parse(): %v, expected error: %v", k, err, tv.Expected.Error)
 		}
 
-		if !areEqualExprs(actual, tv.Expected.Expression) {
+		if !areEqualExprs(singleExpr(actual), tv.Expected.Expression) {
 			t.Fatalf("Failed on test %s\n", k)
 		}
 	}
 }
+
+func TestStreamParser(t *testing.T) {
+	src := "var s = \"日本 ${1 + 2}\";\nfun f(a) { return a; }\nprint f(s);\n"
+
+	p := golox.NewStreamParser(golox.NewScanner(iotest.OneByteReader(strings.NewReader(src))))
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Got error on p.Parse(): %v", err)
+	}
+
+	if len(stmts) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(stmts))
+	}
+
+	p = golox.NewStreamParser(golox.NewScanner(strings.NewReader("print 1;\nprint #;")))
+	if _, err = p.Parse(); err == nil || err.Number != golox.UnexpectedChar || err.Line != 2 {
+		t.Fatalf("Expected the scanner error to be reported, got %v", err)
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	sources := map[string]bool{
+		"fun f(a) {":        true,
+		"fun f(a,":          true,
+		"print (1 +":        true,
+		"print 1":           true,
+		"print \"abc":       true,
+		"print \"a ${1 + 2": true,
+		"var f = (a) =>":    true,
+		"print 1 +;":        false,
+		"print 1; }":        false,
+		"fun f() { print; ": false,
+	}
+
+	for src, incomplete := range sources {
+		_, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(src))).Parse()
+		if err == nil || err.Incomplete() != incomplete {
+			t.Fatalf("Failed on %q. Expected incomplete: %v, got error: %v", src, incomplete, err)
+		}
+	}
+}
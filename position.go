@@ -0,0 +1,88 @@
+package golox
+
+// stmtLine returns the line a statement starts on, or 0 if it is unknown.
+func stmtLine(s Stmt) int {
+	switch st := s.(type) {
+	case *Block:
+		if len(st.Stmts) > 0 {
+			return stmtLine(st.Stmts[0])
+		}
+	case *Expression:
+		return exprLine(st.Expr)
+	case *Print:
+		return st.Keyword.Line
+	case *Var:
+		return st.Name.Line
+	case *Func:
+		return st.Name.Line
+	case *If:
+		return st.Keyword.Line
+	case *While:
+		return st.Keyword.Line
+	case *Return:
+		return st.Keyword.Line
+	case *Throw:
+		return st.Keyword.Line
+	case *Try:
+		return st.Keyword.Line
+	}
+
+	return 0
+}
+
+// exprLine returns the line of the leftmost token of an expression, or 0 if
+// it is unknown. Literals do not keep their token.
+func exprLine(e Expr) int {
+	switch ex := e.(type) {
+	case *Assign:
+		return ex.Name.Line
+	case *Binary:
+		if l := exprLine(ex.Left); l != 0 {
+			return l
+		}
+
+		return ex.Operator.Line
+	case *Grouping:
+		return exprLine(ex.Expr)
+	case *Unary:
+		return ex.Operator.Line
+	case *Call:
+		if l := exprLine(ex.Callee); l != 0 {
+			return l
+		}
+
+		return ex.Paren.Line
+	case *Variable:
+		return ex.Name.Line
+	case *Conditional:
+		if l := exprLine(ex.Condition); l != 0 {
+			return l
+		}
+
+		return ex.Question.Line
+	case *CompoundAssign:
+		return ex.Name.Line
+	case *Increment:
+		if ex.Postfix {
+			return ex.Name.Line
+		}
+
+		return ex.Operator.Line
+	case *Get:
+		if l := exprLine(ex.Object); l != 0 {
+			return l
+		}
+
+		return ex.Name.Line
+	case *Lambda:
+		return ex.Decl.Name.Line
+	case *Logical:
+		if l := exprLine(ex.Left); l != 0 {
+			return l
+		}
+
+		return ex.Operator.Line
+	}
+
+	return 0
+}
@@ -0,0 +1,186 @@
+package golox
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// WritePprof writes the profile as a gzipped protocol buffer in the format
+// read by `go tool pprof`. Every Lox call stack becomes a sample with the
+// number of statements executed and the time spent at its innermost line.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	p.charge()
+
+	b := &protoBuffer{}
+	strs := newStringTable()
+
+	// sample_type
+	for _, st := range [][2]string{{"statements", "count"}, {"time", "nanoseconds"}} {
+		vt := &protoBuffer{}
+		vt.int64Field(1, int64(strs.index(st[0])))
+		vt.int64Field(2, int64(strs.index(st[1])))
+		b.bytesField(1, vt.bytes)
+	}
+
+	// Locations are (function, line) pairs and functions are numbered by id.
+	locations := make(map[profLocation]uint64)
+	funcs := make(map[*FuncProfile]bool)
+
+	for _, s := range p.sortedStacks() {
+		ids := make([]uint64, 0)
+		// pprof lists the innermost location first.
+		for n := s; n != p.stacks; n = n.caller {
+			loc := n.loc
+
+			id, ok := locations[loc]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[loc] = id
+
+				line := &protoBuffer{}
+				line.int64Field(1, int64(loc.fn.id+1))
+				line.int64Field(2, int64(loc.line))
+
+				l := &protoBuffer{}
+				l.int64Field(1, int64(id))
+				l.bytesField(4, line.bytes)
+				b.bytesField(4, l.bytes)
+			}
+
+			if !funcs[loc.fn] {
+				funcs[loc.fn] = true
+
+				f := &protoBuffer{}
+				f.int64Field(1, int64(loc.fn.id+1))
+				f.int64Field(2, int64(strs.index(loc.fn.Name)))
+				f.int64Field(3, int64(strs.index(loc.fn.Name)))
+				f.int64Field(4, int64(strs.index(p.file)))
+				f.int64Field(5, int64(loc.fn.Line))
+				b.bytesField(5, f.bytes)
+			}
+
+			ids = append(ids, id)
+		}
+
+		sample := &protoBuffer{}
+		sample.packedField(1, ids)
+		sample.packedField(2, []uint64{uint64(s.hits), uint64(s.nanos)})
+		b.bytesField(2, sample.bytes)
+	}
+
+	b.int64Field(9, p.start.UnixNano())
+	b.int64Field(10, int64(p.last.Sub(p.start)))
+
+	// period_type and period
+	pt := &protoBuffer{}
+	pt.int64Field(1, int64(strs.index("time")))
+	pt.int64Field(2, int64(strs.index("nanoseconds")))
+	b.bytesField(11, pt.bytes)
+	b.int64Field(12, 1)
+
+	for _, s := range strs.strs {
+		b.bytesField(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.bytes); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// sortedStacks returns the stacks that have a cost, callers first and
+// callees in the order of their locations.
+func (p *Profiler) sortedStacks() []*stackNode {
+	res := make([]*stackNode, 0)
+
+	var walk func(n *stackNode)
+	walk = func(n *stackNode) {
+		if n.hits != 0 || n.nanos != 0 {
+			res = append(res, n)
+		}
+
+		children := make([]*stackNode, 0, len(n.children))
+		for _, c := range n.children {
+			children = append(children, c)
+		}
+
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].loc.fn.id != children[j].loc.fn.id {
+				return children[i].loc.fn.id < children[j].loc.fn.id
+			}
+
+			return children[i].loc.line < children[j].loc.line
+		})
+
+		for _, c := range children {
+			walk(c)
+		}
+	}
+
+	walk(p.stacks)
+
+	return res
+}
+
+type stringTable struct {
+	strs    []string
+	indices map[string]int
+}
+
+// newStringTable creates a table whose first entry is the empty string, as
+// pprof requires.
+func newStringTable() *stringTable {
+	return &stringTable{strs: []string{""}, indices: map[string]int{"": 0}}
+}
+
+func (t *stringTable) index(s string) int {
+	if i, ok := t.indices[s]; ok {
+		return i
+	}
+
+	t.indices[s] = len(t.strs)
+	t.strs = append(t.strs, s)
+
+	return len(t.strs) - 1
+}
+
+// protoBuffer encodes the few protocol buffer wire types pprof uses.
+type protoBuffer struct {
+	bytes []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.bytes = append(b.bytes, byte(x)|0x80)
+		x >>= 7
+	}
+
+	b.bytes = append(b.bytes, byte(x))
+}
+
+func (b *protoBuffer) int64Field(tag int, x int64) {
+	if x == 0 {
+		return
+	}
+
+	b.varint(uint64(tag) << 3)
+	b.varint(uint64(x))
+}
+
+func (b *protoBuffer) bytesField(tag int, data []byte) {
+	b.varint(uint64(tag)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.bytes = append(b.bytes, data...)
+}
+
+func (b *protoBuffer) packedField(tag int, xs []uint64) {
+	packed := &protoBuffer{}
+	for _, x := range xs {
+		packed.varint(x)
+	}
+
+	b.bytesField(tag, packed.bytes)
+}
@@ -0,0 +1,272 @@
+package golox
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Profiler measures where a script spends its time. Every statement executed
+// by the Interpreter is counted against its line, and the time between two
+// consecutive statement boundaries is charged to the innermost running line
+// and function, so the reported times are self times.
+type Profiler struct {
+	file  string
+	funcs map[*Func]*FuncProfile
+	lines map[int]*LineProfile
+	// The root of the tree of Lox call stacks, for pprof.
+	stacks *stackNode
+
+	script *FuncProfile
+	frames []*profFrame
+	last   time.Time
+	start  time.Time
+}
+
+// FuncProfile is the cost of a function. Total includes the time spent in
+// the functions it calls; recursive calls are counted once.
+type FuncProfile struct {
+	Name  string
+	Line  int
+	Calls int
+	Self  time.Duration
+	Total time.Duration
+
+	id      int
+	active  int
+	entered time.Time
+}
+
+// LineProfile is the cost of the statements starting on a line.
+type LineProfile struct {
+	Line int
+	Hits int
+	Self time.Duration
+}
+
+type profFrame struct {
+	fn *FuncProfile
+	// The lines of the statements being executed, innermost last.
+	lines []int
+	// The stack the function was called from.
+	caller *stackNode
+}
+
+// stackNode is a call stack, identified by its innermost location and the
+// stack it was called from, together with its cost.
+type stackNode struct {
+	caller   *stackNode
+	loc      profLocation
+	children map[profLocation]*stackNode
+	hits     int64
+	nanos    int64
+}
+
+func (n *stackNode) child(loc profLocation) *stackNode {
+	c, ok := n.children[loc]
+	if !ok {
+		c = &stackNode{caller: n, loc: loc, children: make(map[profLocation]*stackNode)}
+		n.children[loc] = c
+	}
+
+	return c
+}
+
+type profLocation struct {
+	fn   *FuncProfile
+	line int
+}
+
+// NewProfiler creates a profiler for the script at file, which is only used
+// to name the source in pprof output.
+func NewProfiler(file string) *Profiler {
+	now := time.Now()
+	script := &FuncProfile{Name: "script", Calls: 1, active: 1, entered: now}
+	root := &stackNode{children: make(map[profLocation]*stackNode)}
+
+	return &Profiler{
+		file:   file,
+		funcs:  make(map[*Func]*FuncProfile),
+		lines:  make(map[int]*LineProfile),
+		stacks: root,
+		script: script,
+		frames: []*profFrame{{fn: script, caller: root}},
+		last:   now,
+		start:  now,
+	}
+}
+
+// SetProfiler makes the interpreter report to p. A nil p disables profiling.
+func (interp *Interpreter) SetProfiler(p *Profiler) {
+	interp.profiler = p
+}
+
+// Functions returns the profiled functions sorted by decreasing self time.
+func (p *Profiler) Functions() []*FuncProfile {
+	p.charge()
+
+	res := []*FuncProfile{p.script}
+	for _, f := range p.funcs {
+		res = append(res, f)
+	}
+
+	p.script.Total = p.last.Sub(p.start)
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Self != res[j].Self {
+			return res[i].Self > res[j].Self
+		}
+
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// Lines returns the profiled lines sorted by decreasing self time.
+func (p *Profiler) Lines() []*LineProfile {
+	p.charge()
+
+	res := make([]*LineProfile, 0, len(p.lines))
+	for _, l := range p.lines {
+		res = append(res, l)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Self != res[j].Self {
+			return res[i].Self > res[j].Self
+		}
+
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// WriteReport prints the hottest functions and lines, at most limit of each.
+func (p *Profiler) WriteReport(w io.Writer, limit int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "function\tline\tcalls\tself\ttotal")
+	for i, f := range p.Functions() {
+		if i == limit {
+			break
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", f.Name, f.Line, f.Calls, f.Self, f.Total)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "line\thits\tself")
+	for i, l := range p.Lines() {
+		if i == limit {
+			break
+		}
+
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", l.Line, l.Hits, l.Self)
+	}
+
+	return tw.Flush()
+}
+
+func (p *Profiler) enterStmt(s Stmt) {
+	p.charge()
+
+	top := p.frames[len(p.frames)-1]
+
+	line := stmtLine(s)
+	if line == 0 && len(top.lines) > 0 {
+		line = top.lines[len(top.lines)-1]
+	}
+
+	top.lines = append(top.lines, line)
+
+	if _, ok := s.(*Block); ok || line == 0 {
+		return
+	}
+
+	l, ok := p.lines[line]
+	if !ok {
+		l = &LineProfile{Line: line}
+		p.lines[line] = l
+	}
+
+	l.Hits++
+	p.currentStack().hits++
+}
+
+func (p *Profiler) leaveStmt() {
+	p.charge()
+
+	top := p.frames[len(p.frames)-1]
+	top.lines = top.lines[:len(top.lines)-1]
+}
+
+func (p *Profiler) enterFunc(decl *Func) {
+	p.charge()
+
+	f, ok := p.funcs[decl]
+	if !ok {
+		f = &FuncProfile{Name: decl.Name.Lexeme, Line: decl.Name.Line, id: len(p.funcs) + 1}
+		p.funcs[decl] = f
+	}
+
+	f.Calls++
+	if f.active == 0 {
+		f.entered = p.last
+	}
+	f.active++
+
+	p.frames = append(p.frames, &profFrame{fn: f, caller: p.currentStack()})
+}
+
+func (p *Profiler) leaveFunc() {
+	p.charge()
+
+	f := p.frames[len(p.frames)-1].fn
+	p.frames = p.frames[:len(p.frames)-1]
+
+	f.active--
+	if f.active == 0 {
+		f.Total += p.last.Sub(f.entered)
+	}
+}
+
+// charge attributes the time since the last event to the current line,
+// function and stack.
+func (p *Profiler) charge() {
+	now := time.Now()
+	d := now.Sub(p.last)
+	p.last = now
+
+	top := p.frames[len(p.frames)-1]
+	top.fn.Self += d
+
+	if len(top.lines) > 0 {
+		if l, ok := p.lines[top.lines[len(top.lines)-1]]; ok {
+			l.Self += d
+		}
+	}
+
+	p.currentStack().nanos += int64(d)
+}
+
+func (p *Profiler) currentStack() *stackNode {
+	top := p.frames[len(p.frames)-1]
+
+	return top.caller.child(profLocation{fn: top.fn, line: top.line()})
+}
+
+func (f *profFrame) line() int {
+	if len(f.lines) == 0 {
+		return f.fn.Line
+	}
+
+	return f.lines[len(f.lines)-1]
+}
@@ -0,0 +1,77 @@
+package golox_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+const profiledSource = `fun fib(n) {
+  if (n < 2) return n;
+  return fib(n - 2) + fib(n - 1);
+}
+
+print fib(10);
+`
+
+func TestProfiler(t *testing.T) {
+	stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(profiledSource))).Parse()
+	if err != nil {
+		t.Fatalf("Got error on p.Parse(): %v", err)
+	}
+
+	interp := golox.NewInterpreter()
+	interp.SetOutput(io.Discard)
+
+	p := golox.NewProfiler("fib.lox")
+	interp.SetProfiler(p)
+
+	if err = golox.NewResolver(interp).Resolve(stmts); err != nil {
+		t.Fatalf("Got error on r.Resolve(): %v", err)
+	}
+
+	if _, err = interp.Interpret(stmts); err != nil {
+		t.Fatalf("Got error on interp.Interpret(): %v", err)
+	}
+
+	calls := map[string]int{}
+	for _, f := range p.Functions() {
+		calls[f.Name] = f.Calls
+	}
+
+	if calls["fib"] != 177 {
+		t.Fatalf("Expected 177 calls of fib, got %d", calls["fib"])
+	}
+
+	hits := map[int]int{}
+	for _, l := range p.Lines() {
+		hits[l.Line] = l.Hits
+	}
+
+	// Line 2 counts both the if and the return in its body.
+	expected := map[int]int{1: 1, 2: 177 + 89, 3: 88, 6: 1}
+	for line, n := range expected {
+		if hits[line] != n {
+			t.Fatalf("Expected %d hits on line %d, got %d", n, line, hits[line])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.WritePprof(buf); err != nil {
+		t.Fatalf("Got error on p.WritePprof(): %v", err)
+	}
+
+	zr, err2 := gzip.NewReader(buf)
+	if err2 != nil {
+		t.Fatalf("Expected gzipped pprof output: %v", err2)
+	}
+
+	data, err2 := io.ReadAll(zr)
+	if err2 != nil || !bytes.Contains(data, []byte("fib.lox")) {
+		t.Fatalf("Expected the profile to name the source file, got %q", data)
+	}
+}
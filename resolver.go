@@ -2,14 +2,25 @@ package golox
 
 import "fmt"
 
+// scopeVar is a variable declared in a local scope.
+type scopeVar struct {
+	defined bool
+	// The index of the variable in its Env's slots.
+	slot int
+}
+
 type Resolver struct {
-	scopes      []map[string]bool
+	scopes      []map[string]*scopeVar
 	interpreter *Interpreter
 	curf        FunctionType
+	// The number of enclosing try bodies, and catch clauses followed by a
+	// finally clause, in the current function. Calls returned there are not
+	// tail calls since the try statement must still see their result.
+	tryDepth int
 }
 
 func NewResolver(i *Interpreter) *Resolver {
-	return &Resolver{scopes: make([]map[string]bool, 0), interpreter: i, curf: None}
+	return &Resolver{scopes: make([]map[string]*scopeVar, 0), interpreter: i, curf: None}
 }
 
 func (r *Resolver) Resolve(stmts []Stmt) *LoxError {
@@ -22,168 +33,240 @@ func (r *Resolver) Resolve(stmts []Stmt) *LoxError {
 	return nil
 }
 
-func (r *Resolver) AcceptAssignExpr(a *Assign) (interface{}, *LoxError) {
+func (r *Resolver) AcceptAssignExpr(a *Assign) (Value, *LoxError) {
 	err := r.resolveExpr(a.Value)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	err = r.resolveLocalExpr(a, a.Name)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptBinaryExpr(b *Binary) (interface{}, *LoxError) {
+func (r *Resolver) AcceptBinaryExpr(b *Binary) (Value, *LoxError) {
 	if err := r.resolveExpr(b.Left); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	if err := r.resolveExpr(b.Right); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptGroupingExpr(gr *Grouping) (interface{}, *LoxError) {
-	return nil, r.resolveExpr(gr.Expr)
+func (r *Resolver) AcceptGroupingExpr(gr *Grouping) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(gr.Expr)
 }
 
-func (r *Resolver) AcceptLiteralExpr(*Literal) (interface{}, *LoxError) {
-	return nil, nil
+func (r *Resolver) AcceptLiteralExpr(*Literal) (Value, *LoxError) {
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptUnaryExpr(u *Unary) (interface{}, *LoxError) {
-	return nil, r.resolveExpr(u.Right)
+func (r *Resolver) AcceptUnaryExpr(u *Unary) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(u.Right)
 }
 
-func (r *Resolver) AcceptCallExpr(c *Call) (interface{}, *LoxError) {
+func (r *Resolver) AcceptCallExpr(c *Call) (Value, *LoxError) {
 	if err := r.resolveExpr(c.Callee); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	for _, arg := range c.Args {
 		if err := r.resolveExpr(arg); err != nil {
-			return nil, err
+			return NilValue, err
 		}
 	}
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptVariableExpr(v *Variable) (interface{}, *LoxError) {
+func (r *Resolver) AcceptVariableExpr(v *Variable) (Value, *LoxError) {
 	if len(r.scopes) != 0 {
-		if def, ok := r.scopes[len(r.scopes)-1][v.Name.Lexeme]; ok && !def {
-			return nil, genError(v.Name, SelfInitialization, "Can't read local variable in its own initializer.")
+		if sv, ok := r.scopes[len(r.scopes)-1][v.Name.Lexeme]; ok && !sv.defined {
+			return NilValue, genError(v.Name, SelfInitialization, "Can't read local variable in its own initializer.")
 		}
 	}
 
-	return nil, r.resolveLocalExpr(v, v.Name)
+	return NilValue, r.resolveLocalExpr(v, v.Name)
 }
 
-func (r *Resolver) AcceptLogicalExpr(l *Logical) (interface{}, *LoxError) {
+func (r *Resolver) AcceptLogicalExpr(l *Logical) (Value, *LoxError) {
 	if err := r.resolveExpr(l.Left); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	if err := r.resolveExpr(l.Right); err != nil {
-		return nil, err
+		return NilValue, err
+	}
+
+	return NilValue, nil
+}
+
+func (r *Resolver) AcceptConditionalExpr(c *Conditional) (Value, *LoxError) {
+	for _, e := range []Expr{c.Condition, c.Then, c.Else} {
+		if err := r.resolveExpr(e); err != nil {
+			return NilValue, err
+		}
+	}
+
+	return NilValue, nil
+}
+
+func (r *Resolver) AcceptCompoundAssignExpr(c *CompoundAssign) (Value, *LoxError) {
+	if err := r.resolveExpr(c.Value); err != nil {
+		return NilValue, err
 	}
 
-	return nil, nil
+	return NilValue, r.resolveLocalExpr(c, c.Name)
 }
 
-func (r *Resolver) AcceptBlockStmt(b *Block) (interface{}, *LoxError) {
+func (r *Resolver) AcceptIncrementExpr(i *Increment) (Value, *LoxError) {
+	return NilValue, r.resolveLocalExpr(i, i.Name)
+}
+
+func (r *Resolver) AcceptGetExpr(g *Get) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(g.Object)
+}
+
+func (r *Resolver) AcceptLambdaExpr(l *Lambda) (Value, *LoxError) {
+	return NilValue, r.resolveFunction(l.Decl)
+}
+
+func (r *Resolver) AcceptBlockStmt(b *Block) (Value, *LoxError) {
 	r.beginScope()
 	defer r.endScope()
 
-	return nil, r.resolveBlock(b.Stmts)
+	return NilValue, r.resolveBlock(b.Stmts)
 }
 
-func (r *Resolver) AcceptExpressionStmt(e *Expression) (interface{}, *LoxError) {
-	return nil, r.resolveExpr(e.Expr)
+func (r *Resolver) AcceptExpressionStmt(e *Expression) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(e.Expr)
 }
 
-func (r *Resolver) AcceptPrintStmt(p *Print) (interface{}, *LoxError) {
-	return nil, r.resolveExpr(p.Expr)
+func (r *Resolver) AcceptPrintStmt(p *Print) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(p.Expr)
 }
 
-func (r *Resolver) AcceptVarStmt(v *Var) (interface{}, *LoxError) {
+func (r *Resolver) AcceptVarStmt(v *Var) (Value, *LoxError) {
 	if err := r.declare(v.Name); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	if v.Initializer != nil {
 		if err := r.resolveExpr(v.Initializer); err != nil {
-			return nil, err
+			return NilValue, err
 		}
 	}
 
 	r.define(v.Name)
 
-	return nil, nil
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptFuncStmt(f *Func) (interface{}, *LoxError) {
+func (r *Resolver) AcceptFuncStmt(f *Func) (Value, *LoxError) {
 	if err := r.declare(f.Name); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	r.define(f.Name)
 
-	oldf := r.curf
-	r.curf = Function
-	defer func() {
-		r.curf = oldf
-	}()
-
-	r.beginScope()
-	defer r.endScope()
-
-	for _, p := range f.Params {
-		if err := r.declare(p); err != nil {
-			return nil, err
-		}
-
-		r.define(p)
-	}
-
-	return nil, r.resolveBlock(f.Body)
+	return NilValue, r.resolveFunction(f)
 }
 
-func (r *Resolver) AcceptIfStmt(i *If) (interface{}, *LoxError) {
+func (r *Resolver) AcceptIfStmt(i *If) (Value, *LoxError) {
 	if err := r.resolveExpr(i.Condition); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	if err := r.resolveStmt(i.Body); err != nil {
-		return nil, err
+		return NilValue, err
+	}
+
+	if i.ElseBody != nil {
+		return NilValue, r.resolveStmt(i.ElseBody)
 	}
 
-	return nil, r.resolveStmt(i.ElseBody)
+	return NilValue, nil
 }
 
-func (r *Resolver) AcceptWhileStmt(w *While) (interface{}, *LoxError) {
+func (r *Resolver) AcceptWhileStmt(w *While) (Value, *LoxError) {
 	if err := r.resolveExpr(w.Condition); err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
-	return nil, r.resolveStmt(w.Body)
+	return NilValue, r.resolveStmt(w.Body)
 }
 
-func (r *Resolver) AcceptReturnStmt(ret *Return) (interface{}, *LoxError) {
+func (r *Resolver) AcceptReturnStmt(ret *Return) (Value, *LoxError) {
 	if r.curf == None {
-		return nil, genError(ret.Keyword, ReturnOutsideFunc, "return statement cannot be used outside function.")
+		return NilValue, genError(ret.Keyword, ReturnOutsideFunc, "return statement cannot be used outside function.")
+	}
+
+	if _, ok := ret.Value.(*Call); ok && r.tryDepth == 0 {
+		r.interpreter.resolveTailCall(ret)
 	}
 
 	if ret.Value != nil {
-		return nil, r.resolveExpr(ret.Value)
+		return NilValue, r.resolveExpr(ret.Value)
 	}
 
-	return nil, nil
+	return NilValue, nil
+}
+
+func (r *Resolver) AcceptThrowStmt(t *Throw) (Value, *LoxError) {
+	return NilValue, r.resolveExpr(t.Value)
+}
+
+func (r *Resolver) AcceptTryStmt(t *Try) (Value, *LoxError) {
+	r.tryDepth++
+	err := r.resolveScope(t.Body)
+	r.tryDepth--
+
+	if err != nil {
+		return NilValue, err
+	}
+
+	if t.CatchBody != nil {
+		if t.FinallyBody != nil {
+			r.tryDepth++
+		}
+
+		err = r.resolveCatch(t)
+
+		if t.FinallyBody != nil {
+			r.tryDepth--
+		}
+
+		if err != nil {
+			return NilValue, err
+		}
+	}
+
+	if t.FinallyBody != nil {
+		return NilValue, r.resolveScope(t.FinallyBody)
+	}
+
+	return NilValue, nil
+}
+
+// resolveCatch resolves the catch clause of t in a scope holding the caught
+// value.
+func (r *Resolver) resolveCatch(t *Try) *LoxError {
+	r.beginScope()
+	defer r.endScope()
+
+	if err := r.declare(t.CatchName); err != nil {
+		return err
+	}
+
+	r.define(t.CatchName)
+
+	return r.resolveBlock(t.CatchBody)
 }
 
 func (r *Resolver) resolveExpr(expr Expr) *LoxError {
@@ -200,8 +283,8 @@ func (r *Resolver) resolveStmt(stmt Stmt) *LoxError {
 
 func (r *Resolver) resolveLocalExpr(expr Expr, name Token) *LoxError {
 	for i := 0; i < len(r.scopes); i++ {
-		if _, ok := r.scopes[len(r.scopes)-i-1][name.Lexeme]; ok {
-			r.interpreter.Resolve(expr, i)
+		if sv, ok := r.scopes[len(r.scopes)-i-1][name.Lexeme]; ok {
+			r.interpreter.Resolve(expr, i, sv.slot)
 
 			return nil
 		}
@@ -210,6 +293,27 @@ func (r *Resolver) resolveLocalExpr(expr Expr, name Token) *LoxError {
 	return nil
 }
 
+func (r *Resolver) resolveFunction(f *Func) *LoxError {
+	oldf, oldTryDepth := r.curf, r.tryDepth
+	r.curf, r.tryDepth = Function, 0
+	defer func() {
+		r.curf, r.tryDepth = oldf, oldTryDepth
+	}()
+
+	r.beginScope()
+	defer r.endScope()
+
+	for _, p := range f.Params {
+		if err := r.declare(p); err != nil {
+			return err
+		}
+
+		r.define(p)
+	}
+
+	return r.resolveBlock(f.Body)
+}
+
 func (r *Resolver) declare(name Token) *LoxError {
 	if len(r.scopes) == 0 {
 		return nil
@@ -221,7 +325,7 @@ func (r *Resolver) declare(name Token) *LoxError {
 		return genError(name, NameAlreadyDefined, fmt.Sprintf("Cannot redefine '%s'\n", name.Lexeme))
 	}
 
-	sc[name.Lexeme] = false
+	sc[name.Lexeme] = &scopeVar{slot: len(sc)}
 
 	return nil
 }
@@ -231,7 +335,7 @@ func (r *Resolver) define(name Token) {
 		return
 	}
 
-	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+	r.scopes[len(r.scopes)-1][name.Lexeme].defined = true
 }
 
 func (r *Resolver) resolveBlock(stmts []Stmt) *LoxError {
@@ -243,8 +347,16 @@ func (r *Resolver) resolveBlock(stmts []Stmt) *LoxError {
 	return nil
 }
 
+// resolveScope resolves stmts in a new scope, like a block.
+func (r *Resolver) resolveScope(stmts []Stmt) *LoxError {
+	r.beginScope()
+	defer r.endScope()
+
+	return r.resolveBlock(stmts)
+}
+
 func (r *Resolver) beginScope() {
-	r.scopes = append(r.scopes, make(map[string]bool))
+	r.scopes = append(r.scopes, make(map[string]*scopeVar))
 }
 
 func (r *Resolver) endScope() {
@@ -0,0 +1,82 @@
+package rt
+
+import "fmt"
+
+// Env holds the variables of a scope. The global scope looks its variables
+// up by name; local scopes keep them in slots assigned by the Resolver in
+// declaration order.
+type Env struct {
+	vars      map[string]Value
+	slots     []Value
+	enclosing *Env
+}
+
+func NewEnv(enclosing *Env) *Env {
+	if enclosing == nil {
+		return &Env{vars: make(map[string]Value)}
+	}
+
+	return &Env{enclosing: enclosing}
+}
+
+// Define declares a variable. In a local scope it takes the next slot.
+func (e *Env) Define(name Token, val Value) *LoxError {
+	if e.vars == nil {
+		e.slots = append(e.slots, val)
+
+		return nil
+	}
+
+	if _, ok := e.vars[name.Lexeme]; ok {
+		return NewError(name, NameAlreadyDefined, fmt.Sprintf("Cannot redefine '%s'\n", name.Lexeme))
+	}
+
+	e.vars[name.Lexeme] = val
+
+	return nil
+}
+
+// Vars returns the variables of the global scope by name.
+func (e *Env) Vars() map[string]Value {
+	return e.vars
+}
+
+func (e *Env) GetAt(depth, slot int) Value {
+	return e.ancestor(depth).slots[slot]
+}
+
+func (e *Env) Get(name Token) (Value, *LoxError) {
+	if val, ok := e.vars[name.Lexeme]; ok {
+		return val, nil
+	}
+
+	return NilValue, NewUndefinedVariableError(name)
+}
+
+func (e *Env) AssignAt(depth, slot int, value Value) {
+	e.ancestor(depth).slots[slot] = value
+}
+
+func (e *Env) Assign(name Token, value Value) *LoxError {
+	if _, ok := e.vars[name.Lexeme]; !ok {
+		return NewUndefinedVariableError(name)
+	}
+
+	e.vars[name.Lexeme] = value
+
+	return nil
+}
+
+func (e *Env) ancestor(depth int) *Env {
+	res := e
+
+	for i := 0; i < depth; i++ {
+		if res.enclosing == nil {
+			panic(fmt.Sprintf("Ancestor with the depth %d does not exist since the depth of the env is %d.", depth, i))
+		}
+
+		res = res.enclosing
+	}
+
+	return res
+}
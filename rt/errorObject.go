@@ -0,0 +1,63 @@
+package rt
+
+import "fmt"
+
+// ErrorObject is the value a catch clause binds a runtime error to. Its
+// properties are `message`, `type` (the name of the error number), `line`
+// and `column`.
+type ErrorObject struct {
+	err *LoxError
+}
+
+func NewErrorObject(err *LoxError) *ErrorObject {
+	return &ErrorObject{err: err}
+}
+
+func (e *ErrorObject) Get(name Token) (Value, *LoxError) {
+	switch name.Lexeme {
+	case "message":
+		return StringValue(e.err.Msg), nil
+	case "type":
+		return StringValue(errorNames[e.err.Number]), nil
+	case "line":
+		return IntValue(int64(e.err.Line)), nil
+	case "column":
+		return IntValue(int64(e.err.Col)), nil
+	}
+
+	return NilValue, NewError(name, InvalidProperty, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
+
+func (e *ErrorObject) String() string {
+	return fmt.Sprintf("<error %s: %s>", errorNames[e.err.Number], e.err.Msg)
+}
+
+// getter is implemented by the values whose properties can be read.
+type getter interface {
+	Get(name Token) (Value, *LoxError)
+}
+
+// CaughtValue is the value a catch clause binds err to.
+func CaughtValue(err *LoxError) Value {
+	if err.thrown {
+		return err.Value
+	}
+
+	return ObjectValue(NewErrorObject(err))
+}
+
+// ThrowValue creates the error raised by `throw v`. Throwing a caught runtime
+// error raises it again unchanged.
+func ThrowValue(keyword Token, v Value) *LoxError {
+	if eo, ok := v.AsObject().(*ErrorObject); ok {
+		err := *eo.err
+		err.Value, err.thrown = v, true
+
+		return &err
+	}
+
+	err := NewError(keyword, Thrown, Stringify(v))
+	err.Value, err.thrown = v, true
+
+	return err
+}
@@ -0,0 +1,102 @@
+package rt
+
+import "fmt"
+
+type LoxErrorNumber int
+
+const (
+	UnexpectedChar LoxErrorNumber = iota
+	UnterminatedString
+	UnfinishedExpression
+	UndefinedVariable
+	UnassignedVariable
+	InvalidAssignment
+	ArgumentLimitExceeded
+	InvalidCall
+	InvalidArity
+	ParamLimitExceeded
+	InvalidParamName
+	NameAlreadyDefined
+	ReturnOutsideFunc
+	SelfInitialization
+	InvalidEscape
+	UnexpectedEOF
+	Thrown
+	InvalidProperty
+	InvalidTry
+	InvalidOperand
+	Unsupported
+	UnterminatedComment
+	InvalidNumber
+	DivisionByZero
+	InvalidType
+	TypeMismatch
+)
+
+var errorNames = map[LoxErrorNumber]string{
+	UnexpectedChar:        "Unexpected character",
+	UnterminatedString:    "Unterminated string",
+	UnfinishedExpression:  "Unfinished expression",
+	UndefinedVariable:     "Undefined variable",
+	UnassignedVariable:    "Unassigned variable",
+	InvalidAssignment:     "Invalid assignment",
+	ArgumentLimitExceeded: "Argument limit exceeded",
+	InvalidCall:           "Invalid call",
+	InvalidArity:          "Invalid arity",
+	ParamLimitExceeded:    "Parameter limit exceeded",
+	InvalidParamName:      "Invalid parameter name",
+	NameAlreadyDefined:    "Name already defined",
+	ReturnOutsideFunc:     "Return outside function",
+	SelfInitialization:    "Variable self initialization",
+	InvalidEscape:         "Invalid escape sequence",
+	UnexpectedEOF:         "Unexpected end of input",
+	Thrown:                "Uncaught exception",
+	InvalidProperty:       "Invalid property",
+	InvalidTry:            "Invalid try statement",
+	InvalidOperand:        "Invalid operand",
+	Unsupported:           "Unsupported feature",
+	UnterminatedComment:   "Unterminated comment",
+	InvalidNumber:         "Invalid number literal",
+	DivisionByZero:        "Division by zero",
+	InvalidType:           "Invalid type",
+	TypeMismatch:          "Type mismatch",
+}
+
+type LoxError struct {
+	File   string
+	Line   int
+	Col    int
+	Msg    string
+	Number LoxErrorNumber
+	// The value given to `throw`, valid if thrown is set.
+	Value  Value
+	thrown bool
+	// The calls active when the error happened, innermost first.
+	Trace []string
+}
+
+func (e *LoxError) Error() string {
+	return fmt.Sprintf("ERR '%s': %s:%d:%d: %s", errorNames[e.Number], e.File, e.Line, e.Col, e.Msg)
+}
+
+// Incomplete reports whether the error was caused by the input ending in the
+// middle of a token or a statement, i.e. whether more input could fix it.
+func (e *LoxError) Incomplete() bool {
+	return e.Number == UnexpectedEOF || e.Number == UnterminatedString || e.Number == UnterminatedComment
+}
+
+// NewUndefinedVariableError reports a use of the undefined variable t.
+func NewUndefinedVariableError(t Token) *LoxError {
+	return NewError(t, UndefinedVariable, fmt.Sprintf("Undefined variable %s", t.Lexeme))
+}
+
+// NewError returns the error num with message msg at token t.
+func NewError(t Token, num LoxErrorNumber, msg string) *LoxError {
+	return &LoxError{
+		File:   t.File,
+		Line:   t.Line,
+		Col:    t.Col,
+		Number: num,
+		Msg:    msg,
+	}
+}
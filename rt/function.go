@@ -0,0 +1,107 @@
+package rt
+
+import "fmt"
+
+// Function is a compiled Lox function. Its body returns the value the
+// function returns.
+type Function struct {
+	r     *Runtime
+	name  string
+	arity int
+	body  func(args []Value) Value
+}
+
+// Function returns a Lox function with the given name and arity.
+func (r *Runtime) Function(name string, arity int, body func(args []Value) Value) Value {
+	return ObjectValue(&Function{r: r, name: name, arity: arity, body: body})
+}
+
+func (f *Function) GetArity() int {
+	return f.arity
+}
+
+func (f *Function) Call(args []Value) (res Value, err *LoxError) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = asError(p)
+		}
+	}()
+
+	return f.r.Call(Token{}, f, args...), nil
+}
+
+func (f *Function) String() string {
+	return fmt.Sprintf("<fn %s>", f.name)
+}
+
+// Callee returns what callee holds if it can be called with argc arguments.
+// It is checked before the arguments are evaluated, like in the interpreter.
+func Callee(paren Token, callee Value, argc int) Callable {
+	cf, err := CheckCallee(paren, callee, argc)
+	check(err)
+
+	return cf
+}
+
+// Call calls cf with args. A Lox function is called again as long as it
+// returns with a tail call, so that tail calls run in constant Go stack.
+func (r *Runtime) Call(paren Token, cf Callable, args ...Value) Value {
+	f, ok := cf.(*Function)
+	if !ok {
+		return callNative(paren, cf, args)
+	}
+
+	r.frames = append(r.frames, CallFrame{Name: f.name, Line: paren.Line})
+	defer r.popFrame()
+
+	for {
+		res := f.body(args)
+		if r.tailFn == nil {
+			return res
+		}
+
+		f, args, r.tailFn, r.tailArgs = r.tailFn, r.tailArgs, nil, nil
+		r.frames[len(r.frames)-1].Name = f.name
+	}
+}
+
+// TailCall makes a call in tail position. A Lox function is called by the
+// Call running the current function once it has returned.
+func (r *Runtime) TailCall(paren Token, cf Callable, args ...Value) Value {
+	f, ok := cf.(*Function)
+	if !ok {
+		return callNative(paren, cf, args)
+	}
+
+	r.tailFn, r.tailArgs = f, args
+
+	return NilValue
+}
+
+// popFrame leaves the innermost call, recording the stack trace of the error
+// it fails with, if any.
+func (r *Runtime) popFrame() {
+	if p := recover(); p != nil {
+		if err := asError(p); err.Trace == nil {
+			err.Trace = StackTrace(r.frames, err.Line)
+		}
+
+		r.frames = r.frames[:len(r.frames)-1]
+
+		panic(p)
+	}
+
+	r.frames = r.frames[:len(r.frames)-1]
+}
+
+func callNative(paren Token, cf Callable, args []Value) Value {
+	res, err := cf.Call(args)
+	if err != nil && err.Line == 0 {
+		// Errors of native functions do not know where they were called from.
+		err.File, err.Line, err.Col = paren.File, paren.Line, paren.Col
+	}
+
+	check(err)
+
+	return res
+}
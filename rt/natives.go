@@ -0,0 +1,50 @@
+package rt
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Callable is implemented by the values that can be called: the functions of
+// the interpreter and of compiled programs, and the natives.
+type Callable interface {
+	GetArity() int
+	Call(args []Value) (Value, *LoxError)
+}
+
+// Clock
+
+type LoxClock struct{}
+
+func (l *LoxClock) GetArity() int {
+	return 0
+}
+
+func (l *LoxClock) Call([]Value) (Value, *LoxError) {
+	return NumberValue(float64(time.Now().UnixMilli()) / 1000.0), nil
+}
+
+func (l *LoxClock) String() string {
+	return "<native fn>"
+}
+
+// Len
+
+// LoxLen returns the number of characters (Unicode code points) in a string.
+type LoxLen struct{}
+
+func (l *LoxLen) GetArity() int {
+	return 1
+}
+
+func (l *LoxLen) Call(args []Value) (Value, *LoxError) {
+	if !args[0].IsString() {
+		return NilValue, &LoxError{Number: InvalidCall, Msg: "len() expects a string."}
+	}
+
+	return IntValue(int64(utf8.RuneCountInString(args[0].AsString()))), nil
+}
+
+func (l *LoxLen) String() string {
+	return "<native fn>"
+}
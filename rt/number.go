@@ -0,0 +1,178 @@
+package rt
+
+import (
+	"math"
+	"math/big"
+)
+
+// Arithmetic on numbers. Integers stay integers under +, -, *, ~/ and %,
+// growing into big integers instead of overflowing. As soon as one operand is
+// a floating-point number the operation is done in floating point, and `/`
+// always is, so 7 / 2 is 3.5 while 7 ~/ 2 is 3.
+
+func addNumbers(l, r Value) Value {
+	if !l.IsInt() || !r.IsInt() {
+		return NumberValue(l.AsNumber() + r.AsNumber())
+	}
+
+	if !l.IsBigInt() && !r.IsBigInt() {
+		a, b := l.AsInt(), r.AsInt()
+		if c := a + b; (c > a) == (b > 0) {
+			return IntValue(c)
+		}
+	}
+
+	return BigIntValue(new(big.Int).Add(l.AsBigInt(), r.AsBigInt()))
+}
+
+func subtractNumbers(l, r Value) Value {
+	if !l.IsInt() || !r.IsInt() {
+		return NumberValue(l.AsNumber() - r.AsNumber())
+	}
+
+	if !l.IsBigInt() && !r.IsBigInt() {
+		a, b := l.AsInt(), r.AsInt()
+		if c := a - b; (c < a) == (b > 0) {
+			return IntValue(c)
+		}
+	}
+
+	return BigIntValue(new(big.Int).Sub(l.AsBigInt(), r.AsBigInt()))
+}
+
+func multiplyNumbers(l, r Value) Value {
+	if !l.IsInt() || !r.IsInt() {
+		return NumberValue(l.AsNumber() * r.AsNumber())
+	}
+
+	if !l.IsBigInt() && !r.IsBigInt() {
+		a, b := l.AsInt(), r.AsInt()
+		if a == 0 || b == 0 {
+			return IntValue(0)
+		}
+
+		// MinInt64 * -1 overflows to MinInt64, which the division does not catch.
+		if c := a * b; c/b == a && !(a == math.MinInt64 && b == -1) && !(b == math.MinInt64 && a == -1) {
+			return IntValue(c)
+		}
+	}
+
+	return BigIntValue(new(big.Int).Mul(l.AsBigInt(), r.AsBigInt()))
+}
+
+// divideNumbers always divides in floating point.
+func divideNumbers(l, r Value) Value {
+	return NumberValue(l.AsNumber() / r.AsNumber())
+}
+
+// intDivideNumbers divides the integers l and r, truncating towards zero. It
+// returns false if r is zero.
+func intDivideNumbers(l, r Value) (Value, bool) {
+	if isZero(r) {
+		return NilValue, false
+	}
+
+	if !l.IsBigInt() && !r.IsBigInt() && !(l.AsInt() == math.MinInt64 && r.AsInt() == -1) {
+		return IntValue(l.AsInt() / r.AsInt()), true
+	}
+
+	return BigIntValue(new(big.Int).Quo(l.AsBigInt(), r.AsBigInt())), true
+}
+
+// moduloNumbers returns the remainder of l divided by r, which has the sign of
+// l. For integers it returns false if r is zero.
+func moduloNumbers(l, r Value) (Value, bool) {
+	if !l.IsInt() || !r.IsInt() {
+		return NumberValue(math.Mod(l.AsNumber(), r.AsNumber())), true
+	}
+
+	if isZero(r) {
+		return NilValue, false
+	}
+
+	if !l.IsBigInt() && !r.IsBigInt() {
+		if r.AsInt() == -1 {
+			return IntValue(0), true
+		}
+
+		return IntValue(l.AsInt() % r.AsInt()), true
+	}
+
+	return BigIntValue(new(big.Int).Rem(l.AsBigInt(), r.AsBigInt())), true
+}
+
+func negateNumber(v Value) Value {
+	if !v.IsInt() {
+		return NumberValue(-v.AsNumber())
+	}
+
+	if !v.IsBigInt() && v.AsInt() != math.MinInt64 {
+		return IntValue(-v.AsInt())
+	}
+
+	return BigIntValue(new(big.Int).Neg(v.AsBigInt()))
+}
+
+// compareNumbers returns -1, 0 or 1 depending on whether l is less than, equal
+// to or greater than r. Integers and floating-point numbers are compared
+// exactly. It returns false if either of them is NaN.
+func compareNumbers(l, r Value) (int, bool) {
+	if l.IsInt() && r.IsInt() {
+		if !l.IsBigInt() && !r.IsBigInt() {
+			switch a, b := l.AsInt(), r.AsInt(); {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+
+			return 0, true
+		}
+
+		return l.AsBigInt().Cmp(r.AsBigInt()), true
+	}
+
+	if !l.IsInt() && !r.IsInt() {
+		a, b := l.AsNumber(), r.AsNumber()
+		switch {
+		case math.IsNaN(a) || math.IsNaN(b):
+			return 0, false
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+
+		return 0, true
+	}
+
+	lf, ok := exactFloat(l)
+	if !ok {
+		return 0, false
+	}
+
+	rf, ok := exactFloat(r)
+	if !ok {
+		return 0, false
+	}
+
+	return lf.Cmp(rf), true
+}
+
+// exactFloat converts the number v to a big.Float without rounding. It returns
+// false for NaN.
+func exactFloat(v Value) (*big.Float, bool) {
+	if v.IsInt() {
+		return new(big.Float).SetInt(v.AsBigInt()), true
+	}
+
+	if math.IsNaN(v.AsNumber()) {
+		return nil, false
+	}
+
+	return big.NewFloat(v.AsNumber()), true
+}
+
+func isZero(v Value) bool {
+	return !v.IsBigInt() && v.AsInt() == 0
+}
@@ -0,0 +1,255 @@
+// Package rt is the runtime of Lox programs compiled to Go by `golox build`.
+//
+// It defines the values, tokens, errors, operators and natives of the
+// language. The interpreter uses the same definitions, so compiled programs
+// print the same output and report the same errors without importing it.
+// Runtime errors are raised as panics of *LoxError and recovered by Try and
+// Run.
+package rt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+// Runtime holds the state of a running program: its global variables, its
+// output and the Lox functions being called.
+type Runtime struct {
+	globals *Env
+	out     *bufio.Writer
+	frames  []CallFrame
+	// The function and the arguments of a pending tail call, see TailCall.
+	tailFn   *Function
+	tailArgs []Value
+}
+
+// New returns a Runtime whose print statements write to w.
+func New(w io.Writer) *Runtime {
+	return &Runtime{globals: NewGlobalEnv(), out: bufio.NewWriter(w)}
+}
+
+// Main runs a compiled script writing to stdout and, if it fails, prints the
+// error like the interpreter does and exits.
+func Main(script func(r *Runtime)) {
+	err := New(os.Stdout).Run(script)
+	if err == nil {
+		return
+	}
+
+	fmt.Printf("Error happened: %s\n", err.Error())
+
+	for _, line := range err.Trace {
+		fmt.Printf("    %s\n", line)
+	}
+
+	os.Exit(1)
+}
+
+// Run runs a compiled script and returns the runtime error it failed with,
+// if any.
+func (r *Runtime) Run(script func(r *Runtime)) (err *LoxError) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = asError(p)
+		}
+
+		if ferr := r.out.Flush(); ferr != nil && err == nil {
+			panic(ferr)
+		}
+	}()
+
+	script(r)
+
+	return nil
+}
+
+func (r *Runtime) Print(v Value) {
+	r.out.WriteString(Stringify(v))
+	r.out.WriteByte('\n')
+}
+
+// Globals
+
+func (r *Runtime) DefineGlobal(name Token, v Value) {
+	check(r.globals.Define(name, v))
+}
+
+func (r *Runtime) Global(name Token) Value {
+	v, err := r.globals.Get(name)
+	check(err)
+
+	return Assigned(name, v)
+}
+
+func (r *Runtime) SetGlobal(name Token, v Value) Value {
+	check(r.globals.Assign(name, v))
+
+	return v
+}
+
+// IncrementGlobal applies `++` or `--` to the global variable name and
+// returns its old value if postfix is set and its new one otherwise.
+func (r *Runtime) IncrementGlobal(name, op Token, postfix bool) Value {
+	cur := r.Global(name)
+	v := incremented(op, cur)
+	r.SetGlobal(name, v)
+
+	if postfix {
+		return cur
+	}
+
+	return v
+}
+
+// Locals
+
+// Assigned returns the value v of the local variable name, failing if it has
+// not been assigned yet.
+func Assigned(name Token, v Value) Value {
+	check(CheckAssigned(name, v))
+
+	return v
+}
+
+// Read returns v. A local variable is read through it when an operand to its
+// right has side effects: Go leaves the order of reading variables and making
+// calls in an expression unspecified, but not the order of calls.
+func Read(v Value) Value {
+	return v
+}
+
+// Set assigns v to the local variable at p and returns v.
+func Set(p *Value, v Value) Value {
+	*p = v
+
+	return v
+}
+
+// Increment applies `++` or `--` to the local variable at p, whose current
+// value is cur, and returns its old value if postfix is set and its new one
+// otherwise.
+func Increment(op Token, p *Value, cur Value, postfix bool) Value {
+	v := incremented(op, cur)
+	*p = v
+
+	if postfix {
+		return cur
+	}
+
+	return v
+}
+
+func incremented(op Token, cur Value) Value {
+	v, err := IncrementOp(op, cur)
+	check(err)
+
+	return v
+}
+
+// Operators
+
+func Binary(op Token, l, r Value) Value {
+	v, err := BinaryOp(op, l, r)
+	check(err)
+
+	return v
+}
+
+func Unary(op Token, v Value) Value {
+	v, err := UnaryOp(op, v)
+	check(err)
+
+	return v
+}
+
+func Get(name Token, obj Value) Value {
+	v, err := GetProperty(name, obj)
+	check(err)
+
+	return v
+}
+
+// Errors
+
+func Throw(keyword Token, v Value) {
+	panic(ThrowValue(keyword, v))
+}
+
+// Try runs a try statement whose clauses are compiled to functions reporting
+// whether they returned from the enclosing Lox function, and with what value.
+// catch and finally are nil if the statement has no such clause. Like in the
+// interpreter, finally runs however the statement is left, and an error or a
+// return in finally replaces the error being propagated.
+func (r *Runtime) Try(body func() (Value, bool), catch func(Value) (Value, bool),
+	finally func() (Value, bool)) (ret Value, returned bool) {
+	if finally != nil {
+		defer func() {
+			p := recover()
+			if _, ok := p.(*LoxError); p != nil && !ok {
+				panic(p)
+			}
+
+			if v, ok := finally(); ok {
+				ret, returned = v, true
+
+				return
+			}
+
+			if p != nil {
+				panic(p)
+			}
+		}()
+	}
+
+	ret, returned, err := protect(body)
+	if err == nil || catch == nil {
+		check(err)
+
+		return ret, returned
+	}
+
+	return catch(CaughtValue(err))
+}
+
+// protect runs f and returns the error it fails with, if any.
+func protect(f func() (Value, bool)) (ret Value, returned bool, err *LoxError) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = asError(p)
+		}
+	}()
+
+	ret, returned = f()
+
+	return ret, returned, nil
+}
+
+// asError returns the runtime error p was raised with, or panics with p again
+// if it is something else.
+func asError(p interface{}) *LoxError {
+	err, ok := p.(*LoxError)
+	if !ok {
+		panic(p)
+	}
+
+	return err
+}
+
+func check(err *LoxError) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BigInt returns the integer literal s, which does not fit in an int64.
+func BigInt(s string) Value {
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(fmt.Sprintf("invalid integer literal %q", s))
+	}
+
+	return BigIntValue(b)
+}
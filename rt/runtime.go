@@ -0,0 +1,210 @@
+package rt
+
+import "fmt"
+
+// The operations of the language that the interpreter shares with programs
+// compiled to Go, so that both compute the same values and report the same
+// errors.
+
+// CallFrame is a call to a Lox function, used for stack traces.
+type CallFrame struct {
+	Name string
+	// The line the function was called from.
+	Line int
+}
+
+// StackTrace describes the calls in frames, innermost first, when an error
+// happens on the given line of the innermost one.
+func StackTrace(frames []CallFrame, line int) []string {
+	res := make([]string, 0, len(frames)+1)
+
+	for i := len(frames) - 1; i >= 0; i-- {
+		res = append(res, fmt.Sprintf("at %s (line %d)", frames[i].Name, line))
+		line = frames[i].Line
+	}
+
+	return append(res, fmt.Sprintf("at script (line %d)", line))
+}
+
+// NewGlobalEnv returns a global scope holding the native functions.
+func NewGlobalEnv() *Env {
+	env := NewEnv(nil)
+
+	if err := env.Define(Token{Lexeme: "clock", Type: IDENTIFIER}, ObjectValue(&LoxClock{})); err != nil {
+		panic(err)
+	}
+
+	if err := env.Define(Token{Lexeme: "len", Type: IDENTIFIER}, ObjectValue(&LoxLen{})); err != nil {
+		panic(err)
+	}
+
+	return env
+}
+
+// CheckCallee returns the Callable that callee holds if it can be called with
+// argc arguments.
+func CheckCallee(paren Token, callee Value, argc int) (Callable, *LoxError) {
+	cf, ok := callee.AsObject().(Callable)
+	if !ok {
+		return nil, NewError(paren, InvalidCall, "Can only call functions and classes.")
+	}
+
+	if cf.GetArity() != argc {
+		return nil, NewError(paren, InvalidArity, fmt.Sprintf("Expected %d arguments but got %d.", cf.GetArity(), argc))
+	}
+
+	return cf, nil
+}
+
+// CheckAssigned reports reading the variable name whose value is val before
+// it has been assigned.
+func CheckAssigned(name Token, val Value) *LoxError {
+	if val.Type() != uninitializedType {
+		return nil
+	}
+
+	return NewError(name, UnassignedVariable, fmt.Sprintf("Usage of unassigned variable %s", name.Lexeme))
+}
+
+// GetProperty returns the property name of obj.
+func GetProperty(name Token, obj Value) (Value, *LoxError) {
+	o, ok := obj.AsObject().(getter)
+	if !ok {
+		return NilValue, NewError(name, InvalidProperty, "Only objects have properties.")
+	}
+
+	return o.Get(name)
+}
+
+// UnaryOp applies the unary operator op to v.
+func UnaryOp(op Token, v Value) (Value, *LoxError) {
+	switch op.Type {
+	case MINUS:
+		if err := checkNumberOperand(op, v); err != nil {
+			return NilValue, err
+		}
+
+		return negateNumber(v), nil
+	case BANG:
+		return BoolValue(!v.IsTruthy()), nil
+	case INTERPOLATION:
+		return StringValue(Stringify(v)), nil
+	}
+
+	return NilValue, &LoxError{
+		Number: Unsupported, File: op.File, Line: op.Line, Col: op.Col,
+		Msg: fmt.Sprintf("Unsupported operator type `%s`.", op.Lexeme),
+	}
+}
+
+// IncrementOp returns the value `++` or `--` assigns to a variable holding v.
+func IncrementOp(op Token, v Value) (Value, *LoxError) {
+	if err := checkNumberOperand(op, v); err != nil {
+		return NilValue, err
+	}
+
+	if op.Type == MINUS_MINUS {
+		return subtractNumbers(v, IntValue(1)), nil
+	}
+
+	return addNumbers(v, IntValue(1)), nil
+}
+
+// BinaryOp applies the binary operator op to lv and rv.
+func BinaryOp(op Token, lv, rv Value) (Value, *LoxError) {
+	switch op.Type {
+	case MINUS, STAR, SLASH, PERCENT, TILDE_SLASH, GREATER, GREATER_EQUAL, LESS, LESS_EQUAL:
+		if err := checkNumberOperands(op, lv, rv); err != nil {
+			return NilValue, err
+		}
+	}
+
+	switch op.Type {
+	case MINUS:
+		return subtractNumbers(lv, rv), nil
+	case STAR:
+		return multiplyNumbers(lv, rv), nil
+	case SLASH:
+		return divideNumbers(lv, rv), nil
+	case TILDE_SLASH:
+		if !lv.IsInt() || !rv.IsInt() {
+			return NilValue, NewError(op, InvalidOperand, "Operands of `~/` must be integers.")
+		}
+
+		res, ok := intDivideNumbers(lv, rv)
+		if !ok {
+			return NilValue, NewError(op, DivisionByZero, "Integer division by zero.")
+		}
+
+		return res, nil
+	case PERCENT:
+		res, ok := moduloNumbers(lv, rv)
+		if !ok {
+			return NilValue, NewError(op, DivisionByZero, "Integer modulo by zero.")
+		}
+
+		return res, nil
+	case PLUS:
+		if lv.IsNumber() && rv.IsNumber() {
+			return addNumbers(lv, rv), nil
+		}
+
+		if lv.IsString() && rv.IsString() {
+			return StringValue(lv.AsString() + rv.AsString()), nil
+		}
+
+		return NilValue, &LoxError{
+			Number: InvalidOperand, File: op.File, Line: op.Line, Col: op.Col,
+			Msg: "Operands must be two numbers or two strings.",
+		}
+	case GREATER:
+		c, ok := compareNumbers(lv, rv)
+
+		return BoolValue(ok && c > 0), nil
+	case GREATER_EQUAL:
+		c, ok := compareNumbers(lv, rv)
+
+		return BoolValue(ok && c >= 0), nil
+	case LESS:
+		c, ok := compareNumbers(lv, rv)
+
+		return BoolValue(ok && c < 0), nil
+	case LESS_EQUAL:
+		c, ok := compareNumbers(lv, rv)
+
+		return BoolValue(ok && c <= 0), nil
+	case BANG_EQUAL:
+		return BoolValue(!lv.Equals(rv)), nil
+	case EQUAL_EQUAL:
+		return BoolValue(lv.Equals(rv)), nil
+	case COMMA:
+		return rv, nil
+	}
+
+	return NilValue, &LoxError{
+		Number: Unsupported, File: op.File, Line: op.Line, Col: op.Col,
+		Msg: fmt.Sprintf("Unsupported operator type `%s`.", op.Lexeme),
+	}
+}
+
+func checkNumberOperand(op Token, r Value) *LoxError {
+	if !r.IsNumber() {
+		return &LoxError{
+			Number: InvalidOperand, File: op.File, Line: op.Line, Col: op.Col,
+			Msg: fmt.Sprintf("Expected a number but found `%s`.", op.Lexeme),
+		}
+	}
+
+	return nil
+}
+
+func checkNumberOperands(op Token, l, r Value) *LoxError {
+	if !l.IsNumber() || !r.IsNumber() {
+		return &LoxError{
+			Number: InvalidOperand, File: op.File, Line: op.Line, Col: op.Col,
+			Msg: "Operands must be numbers.",
+		}
+	}
+
+	return nil
+}
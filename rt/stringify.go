@@ -0,0 +1,60 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Stringify converts a Lox value to the text printed for it. The format
+// follows the reference implementations:
+//
+//	nil, true, false
+//	numbers     integers without a fractional part (3, -0, 1e+21 and above
+//	            in exponent form), others in their shortest form (0.1),
+//	            nan, inf and -inf
+//	integers    in decimal, however big
+//	strings     their contents
+//	functions   <fn name>, natives <native fn>
+func Stringify(v Value) string {
+	switch v.typ {
+	case NilType:
+		return "nil"
+	case BoolType:
+		return strconv.FormatBool(v.AsBool())
+	case NumberType:
+		return formatNumber(v.num)
+	case IntType:
+		if v.IsBigInt() {
+			return v.obj.(*big.Int).String()
+		}
+
+		return strconv.FormatInt(v.AsInt(), 10)
+	case StringType:
+		return v.AsString()
+	case uninitializedType:
+		return "<uninitialized>"
+	}
+
+	if s, ok := v.obj.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return fmt.Sprint(v.obj)
+}
+
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	case n == math.Trunc(n) && math.Abs(n) < 1e21:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
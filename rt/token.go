@@ -0,0 +1,152 @@
+package rt
+
+import "fmt"
+
+type TokenType int
+
+const (
+	NONE TokenType = iota
+
+	// Single-character tokens.
+	LEFT_PAREN
+	RIGHT_PAREN
+	LEFT_BRACE
+	RIGHT_BRACE
+	COMMA
+	DOT
+	MINUS
+	PLUS
+	SEMICOLON
+	SLASH
+	STAR
+	PERCENT
+	QUESTION
+	COLON
+
+	// One or two character tokens.
+	BANG
+	BANG_EQUAL
+	EQUAL
+	EQUAL_EQUAL
+	GREATER
+	GREATER_EQUAL
+	LESS
+	LESS_EQUAL
+	ARROW
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	PERCENT_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+	TILDE_SLASH
+
+	// Literals.
+	IDENTIFIER
+	STRING
+	NUMBER
+	// The part of a string literal preceding an interpolated expression.
+	INTERPOLATION
+
+	// Keywords.
+	AND
+	CLASS
+	ELSE
+	FALSE
+	FUN
+	FOR
+	IF
+	NIL
+	OR
+	PRINT
+	RETURN
+	SUPER
+	THIS
+	TRUE
+	VAR
+	WHILE
+	THROW
+	TRY
+	CATCH
+	FINALLY
+
+	EOF
+)
+
+var tokenNames = map[TokenType]string{
+	NONE:          "none",
+	LEFT_PAREN:    "(",
+	RIGHT_PAREN:   ")",
+	LEFT_BRACE:    "{",
+	RIGHT_BRACE:   "}",
+	COMMA:         ",",
+	DOT:           ".",
+	MINUS:         "-",
+	PLUS:          "+",
+	SEMICOLON:     ";",
+	SLASH:         "/",
+	STAR:          "*",
+	PERCENT:       "%",
+	QUESTION:      "?",
+	COLON:         ":",
+	BANG:          "!",
+	BANG_EQUAL:    "!=",
+	EQUAL:         "=",
+	EQUAL_EQUAL:   "==",
+	GREATER:       ">",
+	GREATER_EQUAL: ">=",
+	LESS:          "<",
+	LESS_EQUAL:    "<=",
+	ARROW:         "=>",
+	PLUS_EQUAL:    "+=",
+	MINUS_EQUAL:   "-=",
+	STAR_EQUAL:    "*=",
+	SLASH_EQUAL:   "/=",
+	PERCENT_EQUAL: "%=",
+	PLUS_PLUS:     "++",
+	MINUS_MINUS:   "--",
+	TILDE_SLASH:   "~/",
+	IDENTIFIER:    "identifier",
+	STRING:        "string",
+	NUMBER:        "number",
+	INTERPOLATION: "string interpolation",
+	AND:           "and",
+	CLASS:         "class",
+	ELSE:          "else",
+	FALSE:         "false",
+	FUN:           "fun",
+	FOR:           "for",
+	IF:            "if",
+	NIL:           "nil",
+	OR:            "or",
+	PRINT:         "print",
+	RETURN:        "return",
+	SUPER:         "super",
+	THIS:          "this",
+	TRUE:          "true",
+	VAR:           "var",
+	WHILE:         "while",
+	THROW:         "throw",
+	TRY:           "try",
+	CATCH:         "catch",
+	FINALLY:       "finally",
+	EOF:           "EOF",
+}
+
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+type Token struct {
+	Type    TokenType
+	Lexeme  string
+	Literal interface{}
+	File    string
+	Line    int
+	Col     int
+}
@@ -0,0 +1,220 @@
+package rt
+
+import (
+	"math"
+	"math/big"
+)
+
+type ValueType uint8
+
+const (
+	NilType ValueType = iota
+	BoolType
+	// Floating-point numbers.
+	NumberType
+	// Integers, stored inline while they fit in an int64 and as a *big.Int
+	// otherwise. Integers are numbers too, see IsNumber.
+	IntType
+	StringType
+	// Functions, natives and any other Go value implementing Lox behaviour,
+	// such as Callable.
+	ObjectType
+	// The value of variables declared without an initializer.
+	uninitializedType
+)
+
+var valueTypeNames = map[ValueType]string{
+	NilType:           "nil",
+	BoolType:          "bool",
+	NumberType:        "number",
+	IntType:           "int",
+	StringType:        "string",
+	ObjectType:        "object",
+	uninitializedType: "uninitialized",
+}
+
+func (t ValueType) String() string {
+	return valueTypeNames[t]
+}
+
+// Value is a Lox value. Nil, booleans, numbers and integers that fit in an
+// int64 are stored inline and never allocate; big integers, strings and
+// objects are kept in obj.
+type Value struct {
+	typ ValueType
+	// The number, or 1 and 0 for true and false. Integers that are not big
+	// store their bits here, so that Values stay small.
+	num float64
+	obj interface{}
+}
+
+// NilValue is the Lox nil. It is also the zero Value.
+var NilValue = Value{}
+
+// Uninitialized is the value of variables declared without an initializer,
+// which cannot be read until they are assigned.
+var Uninitialized = Value{typ: uninitializedType}
+
+func BoolValue(b bool) Value {
+	if b {
+		return Value{typ: BoolType, num: 1}
+	}
+
+	return Value{typ: BoolType}
+}
+
+func NumberValue(n float64) Value {
+	return Value{typ: NumberType, num: n}
+}
+
+func IntValue(i int64) Value {
+	return Value{typ: IntType, num: math.Float64frombits(uint64(i))}
+}
+
+// BigIntValue returns the integer b, which is stored inline if it fits in an
+// int64. b must not be modified afterwards.
+func BigIntValue(b *big.Int) Value {
+	if b.IsInt64() {
+		return IntValue(b.Int64())
+	}
+
+	return Value{typ: IntType, obj: b}
+}
+
+func StringValue(s string) Value {
+	return Value{typ: StringType, obj: s}
+}
+
+// ObjectValue wraps an object. Objects are compared by identity, so o should
+// be a pointer.
+func ObjectValue(o interface{}) Value {
+	return Value{typ: ObjectType, obj: o}
+}
+
+func (v Value) Type() ValueType {
+	return v.typ
+}
+
+func (v Value) IsNil() bool {
+	return v.typ == NilType
+}
+
+func (v Value) IsBool() bool {
+	return v.typ == BoolType
+}
+
+// IsNumber reports whether v is a number, either floating-point or integer.
+func (v Value) IsNumber() bool {
+	return v.typ == NumberType || v.typ == IntType
+}
+
+func (v Value) IsInt() bool {
+	return v.typ == IntType
+}
+
+// IsBigInt reports whether v is an integer that does not fit in an int64.
+func (v Value) IsBigInt() bool {
+	return v.typ == IntType && v.obj != nil
+}
+
+func (v Value) IsString() bool {
+	return v.typ == StringType
+}
+
+func (v Value) IsObject() bool {
+	return v.typ == ObjectType
+}
+
+func (v Value) AsBool() bool {
+	return v.num != 0
+}
+
+// AsNumber returns the number as a float64, rounding integers if needed.
+func (v Value) AsNumber() float64 {
+	if v.typ != IntType {
+		return v.num
+	}
+
+	if v.obj == nil {
+		return float64(v.AsInt())
+	}
+
+	f, _ := new(big.Float).SetInt(v.obj.(*big.Int)).Float64()
+
+	return f
+}
+
+// AsInt returns the integer, which must fit in an int64.
+func (v Value) AsInt() int64 {
+	return int64(math.Float64bits(v.num))
+}
+
+// AsBigInt returns the integer as a new *big.Int.
+func (v Value) AsBigInt() *big.Int {
+	if v.obj == nil {
+		return big.NewInt(v.AsInt())
+	}
+
+	return new(big.Int).Set(v.obj.(*big.Int))
+}
+
+func (v Value) AsString() string {
+	s, _ := v.obj.(string)
+
+	return s
+}
+
+func (v Value) AsObject() interface{} {
+	if v.typ != ObjectType {
+		return nil
+	}
+
+	return v.obj
+}
+
+// IsTruthy follows Lox semantics: nil and false are falsey, everything else
+// is truthy.
+func (v Value) IsTruthy() bool {
+	switch v.typ {
+	case NilType:
+		return false
+	case BoolType:
+		return v.AsBool()
+	default:
+		return true
+	}
+}
+
+// Equals compares values of the same type by value and objects by identity.
+// Integers and floating-point numbers are compared by value too, so 1 == 1.0.
+// Values of other different types are never equal.
+func (v Value) Equals(o Value) bool {
+	if v.typ != o.typ {
+		if v.IsNumber() && o.IsNumber() {
+			c, ok := compareNumbers(v, o)
+
+			return ok && c == 0
+		}
+
+		return false
+	}
+
+	switch v.typ {
+	case NilType, uninitializedType:
+		return true
+	case BoolType, NumberType:
+		return v.num == o.num
+	case IntType:
+		if v.obj == nil || o.obj == nil {
+			return v.obj == nil && o.obj == nil && v.AsInt() == o.AsInt()
+		}
+
+		return v.obj.(*big.Int).Cmp(o.obj.(*big.Int)) == 0
+	default:
+		return v.obj == o.obj
+	}
+}
+
+func (v Value) String() string {
+	return Stringify(v)
+}
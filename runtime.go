@@ -0,0 +1,213 @@
+package golox
+
+import (
+	"math/big"
+
+	"github.com/agayev169/golox/rt"
+)
+
+// The values, tokens, errors and operations of the language are defined in
+// the rt package, which the interpreter shares with programs compiled to Go
+// so that they do not depend on the interpreter.
+
+type (
+	ValueType      = rt.ValueType
+	Value          = rt.Value
+	TokenType      = rt.TokenType
+	Token          = rt.Token
+	LoxErrorNumber = rt.LoxErrorNumber
+	LoxError       = rt.LoxError
+	CallFrame      = rt.CallFrame
+	Env            = rt.Env
+	ErrorObject    = rt.ErrorObject
+	Callable       = rt.Callable
+	LoxClock       = rt.LoxClock
+	LoxLen         = rt.LoxLen
+)
+
+const (
+	NilType    = rt.NilType
+	BoolType   = rt.BoolType
+	NumberType = rt.NumberType
+	IntType    = rt.IntType
+	StringType = rt.StringType
+	ObjectType = rt.ObjectType
+)
+
+const (
+	NONE          = rt.NONE
+	LEFT_PAREN    = rt.LEFT_PAREN
+	RIGHT_PAREN   = rt.RIGHT_PAREN
+	LEFT_BRACE    = rt.LEFT_BRACE
+	RIGHT_BRACE   = rt.RIGHT_BRACE
+	COMMA         = rt.COMMA
+	DOT           = rt.DOT
+	MINUS         = rt.MINUS
+	PLUS          = rt.PLUS
+	SEMICOLON     = rt.SEMICOLON
+	SLASH         = rt.SLASH
+	STAR          = rt.STAR
+	PERCENT       = rt.PERCENT
+	QUESTION      = rt.QUESTION
+	COLON         = rt.COLON
+	BANG          = rt.BANG
+	BANG_EQUAL    = rt.BANG_EQUAL
+	EQUAL         = rt.EQUAL
+	EQUAL_EQUAL   = rt.EQUAL_EQUAL
+	GREATER       = rt.GREATER
+	GREATER_EQUAL = rt.GREATER_EQUAL
+	LESS          = rt.LESS
+	LESS_EQUAL    = rt.LESS_EQUAL
+	ARROW         = rt.ARROW
+	PLUS_EQUAL    = rt.PLUS_EQUAL
+	MINUS_EQUAL   = rt.MINUS_EQUAL
+	STAR_EQUAL    = rt.STAR_EQUAL
+	SLASH_EQUAL   = rt.SLASH_EQUAL
+	PERCENT_EQUAL = rt.PERCENT_EQUAL
+	PLUS_PLUS     = rt.PLUS_PLUS
+	MINUS_MINUS   = rt.MINUS_MINUS
+	TILDE_SLASH   = rt.TILDE_SLASH
+	IDENTIFIER    = rt.IDENTIFIER
+	STRING        = rt.STRING
+	NUMBER        = rt.NUMBER
+	INTERPOLATION = rt.INTERPOLATION
+	AND           = rt.AND
+	CLASS         = rt.CLASS
+	ELSE          = rt.ELSE
+	FALSE         = rt.FALSE
+	FUN           = rt.FUN
+	FOR           = rt.FOR
+	IF            = rt.IF
+	NIL           = rt.NIL
+	OR            = rt.OR
+	PRINT         = rt.PRINT
+	RETURN        = rt.RETURN
+	SUPER         = rt.SUPER
+	THIS          = rt.THIS
+	TRUE          = rt.TRUE
+	VAR           = rt.VAR
+	WHILE         = rt.WHILE
+	THROW         = rt.THROW
+	TRY           = rt.TRY
+	CATCH         = rt.CATCH
+	FINALLY       = rt.FINALLY
+	EOF           = rt.EOF
+)
+
+const (
+	UnexpectedChar        = rt.UnexpectedChar
+	UnterminatedString    = rt.UnterminatedString
+	UnfinishedExpression  = rt.UnfinishedExpression
+	UndefinedVariable     = rt.UndefinedVariable
+	UnassignedVariable    = rt.UnassignedVariable
+	InvalidAssignment     = rt.InvalidAssignment
+	ArgumentLimitExceeded = rt.ArgumentLimitExceeded
+	InvalidCall           = rt.InvalidCall
+	InvalidArity          = rt.InvalidArity
+	ParamLimitExceeded    = rt.ParamLimitExceeded
+	InvalidParamName      = rt.InvalidParamName
+	NameAlreadyDefined    = rt.NameAlreadyDefined
+	ReturnOutsideFunc     = rt.ReturnOutsideFunc
+	SelfInitialization    = rt.SelfInitialization
+	InvalidEscape         = rt.InvalidEscape
+	UnexpectedEOF         = rt.UnexpectedEOF
+	Thrown                = rt.Thrown
+	InvalidProperty       = rt.InvalidProperty
+	InvalidTry            = rt.InvalidTry
+	InvalidOperand        = rt.InvalidOperand
+	Unsupported           = rt.Unsupported
+	UnterminatedComment   = rt.UnterminatedComment
+	InvalidNumber         = rt.InvalidNumber
+	DivisionByZero        = rt.DivisionByZero
+	InvalidType           = rt.InvalidType
+	TypeMismatch          = rt.TypeMismatch
+)
+
+var (
+	NilValue      = rt.NilValue
+	Uninitialized = rt.Uninitialized
+)
+
+func BoolValue(b bool) Value {
+	return rt.BoolValue(b)
+}
+
+func NumberValue(n float64) Value {
+	return rt.NumberValue(n)
+}
+
+func IntValue(i int64) Value {
+	return rt.IntValue(i)
+}
+
+func BigIntValue(b *big.Int) Value {
+	return rt.BigIntValue(b)
+}
+
+func StringValue(s string) Value {
+	return rt.StringValue(s)
+}
+
+func ObjectValue(o interface{}) Value {
+	return rt.ObjectValue(o)
+}
+
+func Stringify(v Value) string {
+	return rt.Stringify(v)
+}
+
+func StackTrace(frames []CallFrame, line int) []string {
+	return rt.StackTrace(frames, line)
+}
+
+func NewGlobalEnv() *Env {
+	return rt.NewGlobalEnv()
+}
+
+func CheckCallee(paren Token, callee Value, argc int) (Callable, *LoxError) {
+	return rt.CheckCallee(paren, callee, argc)
+}
+
+func CheckAssigned(name Token, val Value) *LoxError {
+	return rt.CheckAssigned(name, val)
+}
+
+func GetProperty(name Token, obj Value) (Value, *LoxError) {
+	return rt.GetProperty(name, obj)
+}
+
+func UnaryOp(op Token, v Value) (Value, *LoxError) {
+	return rt.UnaryOp(op, v)
+}
+
+func IncrementOp(op Token, v Value) (Value, *LoxError) {
+	return rt.IncrementOp(op, v)
+}
+
+func BinaryOp(op Token, lv, rv Value) (Value, *LoxError) {
+	return rt.BinaryOp(op, lv, rv)
+}
+
+func NewEnv(enclosing *Env) *Env {
+	return rt.NewEnv(enclosing)
+}
+
+func NewErrorObject(err *LoxError) *ErrorObject {
+	return rt.NewErrorObject(err)
+}
+
+func CaughtValue(err *LoxError) Value {
+	return rt.CaughtValue(err)
+}
+
+func ThrowValue(keyword Token, v Value) *LoxError {
+	return rt.ThrowValue(keyword, v)
+}
+
+func genError(t Token, num LoxErrorNumber, msg string) *LoxError {
+	return rt.NewError(t, num, msg)
+}
+
+func genUndefVarError(t Token) *LoxError {
+	return rt.NewUndefinedVariableError(t)
+}
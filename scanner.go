@@ -1,61 +1,143 @@
 package golox
 
 import (
-	"bytes"
+	"bufio"
+	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":     AND,
+	"class":   CLASS,
+	"else":    ELSE,
+	"false":   FALSE,
+	"for":     FOR,
+	"fun":     FUN,
+	"if":      IF,
+	"nil":     NIL,
+	"or":      OR,
+	"print":   PRINT,
+	"return":  RETURN,
+	"super":   SUPER,
+	"this":    THIS,
+	"true":    TRUE,
+	"var":     VAR,
+	"while":   WHILE,
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
 }
 
+// Scanner produces tokens on demand from an arbitrary reader, so the source
+// never has to be held in memory as a whole.
 type Scanner struct {
-	source     *bytes.Reader
+	source *bufio.Reader
+	// Tokens scanned but not yet returned by Next.
 	tokens     []Token
 	line       int
 	col        int
+	startLine  int
+	startCol   int
 	curTokenSb strings.Builder
+	// Number of unclosed `{` for every string interpolation being scanned.
+	interpolations []int
+	// Size in bytes of the last rune read, 1 for an invalid encoding read as
+	// utf8.RuneError.
+	runeSize int
+	err      error
+	done     bool
 }
 
-func NewScanner(r *bytes.Reader) *Scanner {
-	return &Scanner{source: r, tokens: make([]Token, 0), line: 0, col: 0, curTokenSb: strings.Builder{}}
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{source: bufio.NewReader(r), tokens: make([]Token, 0), line: 1, col: 0, curTokenSb: strings.Builder{}, interpolations: make([]int, 0)}
 }
 
+// ScanTokens scans the whole remaining input.
 func (s *Scanner) ScanTokens() ([]Token, error) {
-	for !s.isAtEnd() {
-		err := s.scanToken()
-		if err != nil {
-			return nil, err
+	res := make([]Token, 0)
+
+	for {
+		t := s.Next()
+		if s.err != nil {
+			return nil, s.err
+		}
+
+		res = append(res, t)
+
+		if t.Type == EOF {
+			return res, nil
 		}
 	}
+}
+
+// Next returns the next token of the input. Once the input is exhausted or an
+// error occurs, it keeps returning an EOF token and Err reports the error.
+func (s *Scanner) Next() Token {
+	for len(s.tokens) == 0 {
+		if s.done {
+			s.startLine, s.startCol = s.line, s.col+1
+			s.addToken(EOF, nil)
 
-	s.addToken(EOF, nil)
-	return s.tokens, nil
+			break
+		}
+
+		s.scanNext()
+	}
+
+	t := s.tokens[0]
+	s.tokens = s.tokens[1:]
+
+	return t
+}
+
+// Err returns the first error encountered while scanning.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+func (s *Scanner) scanNext() {
+	if s.isAtEnd() {
+		s.done = true
+
+		if len(s.interpolations) > 0 && s.err == nil {
+			s.err = &LoxError{Number: UnterminatedString, File: "", Line: s.line, Col: s.col, Msg: "Unterminated string interpolation. Expected }"}
+		}
+
+		return
+	}
+
+	if err := s.scanToken(); err != nil || s.err != nil {
+		// An error reading the input takes precedence over the errors the
+		// truncated input causes.
+		if s.err == nil {
+			s.err = err
+		}
+
+		s.done = true
+	}
 }
 
 func (s *Scanner) isAtEnd() bool {
-	return s.source.Len() <= 0
+	if s.err != nil {
+		return true
+	}
+
+	_, err := s.source.Peek(1)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+
+	return err != nil
 }
 
 func (s *Scanner) scanToken() error {
 	s.curTokenSb.Reset()
+	s.startLine, s.startCol = s.line, s.col+1
 	b := s.readNext()
 
 	var typ TokenType = NONE
@@ -67,47 +149,96 @@ func (s *Scanner) scanToken() error {
 	case ')':
 		typ = RIGHT_PAREN
 	case '{':
+		if len(s.interpolations) > 0 {
+			s.interpolations[len(s.interpolations)-1]++
+		}
+
 		typ = LEFT_BRACE
 	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				// The interpolated expression is over, continue with the rest of the string.
+				s.interpolations = s.interpolations[:n-1]
+
+				return s.parseString()
+			}
+
+			s.interpolations[n-1]--
+		}
+
 		typ = RIGHT_BRACE
 	case ',':
 		typ = COMMA
 	case '.':
 		typ = DOT
 	case '-':
-		typ = MINUS
+		if s.match('-') {
+			typ = MINUS_MINUS
+		} else if s.match('=') {
+			typ = MINUS_EQUAL
+		} else {
+			typ = MINUS
+		}
 	case '+':
-		typ = PLUS
+		if s.match('+') {
+			typ = PLUS_PLUS
+		} else if s.match('=') {
+			typ = PLUS_EQUAL
+		} else {
+			typ = PLUS
+		}
 	case ';':
 		typ = SEMICOLON
 	case '*':
-		typ = STAR
+		if s.match('=') {
+			typ = STAR_EQUAL
+		} else {
+			typ = STAR
+		}
+	case '%':
+		if s.match('=') {
+			typ = PERCENT_EQUAL
+		} else {
+			typ = PERCENT
+		}
+	case '~':
+		if !s.match('/') {
+			return &LoxError{Number: UnexpectedChar, File: "", Line: s.startLine, Col: s.startCol, Msg: "Unexpected character."}
+		}
+
+		typ = TILDE_SLASH
+	case '?':
+		typ = QUESTION
+	case ':':
+		typ = COLON
 	case '!':
-		if s.match("=") {
+		if s.match('=') {
 			typ = BANG_EQUAL
 		} else {
 			typ = BANG
 		}
 	case '=':
-		if s.match("=") {
+		if s.match('=') {
 			typ = EQUAL_EQUAL
+		} else if s.match('>') {
+			typ = ARROW
 		} else {
 			typ = EQUAL
 		}
 	case '<':
-		if s.match("=") {
+		if s.match('=') {
 			typ = LESS_EQUAL
 		} else {
 			typ = LESS
 		}
 	case '>':
-		if s.match("=") {
+		if s.match('=') {
 			typ = GREATER_EQUAL
 		} else {
 			typ = GREATER
 		}
 	case '/':
-		if s.match("/") {
+		if s.match('/') {
 			for !s.isAtEnd() {
 				b := s.peek()
 
@@ -117,6 +248,10 @@ func (s *Scanner) scanToken() error {
 
 				s.readNext()
 			}
+		} else if s.match('*') {
+			return s.skipBlockComment()
+		} else if s.match('=') {
+			typ = SLASH_EQUAL
 		} else {
 			typ = SLASH
 		}
@@ -125,16 +260,18 @@ func (s *Scanner) scanToken() error {
 	case '\t':
 	case '\n':
 		// Ignore whitespace.
-        break
+		break
 	case '"':
-		s.parseString()
+		return s.parseString()
 	default:
-		if isDigit(b) {
-			s.parseNumber()
+		if b == utf8.RuneError && s.runeSize == 1 {
+			return &LoxError{Number: UnexpectedChar, File: "", Line: s.startLine, Col: s.startCol, Msg: "Invalid UTF-8 encoding."}
+		} else if isDigit(b) {
+			return s.parseNumber(b)
 		} else if isAlpha(b) {
 			s.parseIdentifier()
 		} else {
-			return &LoxError{Number: UnexpectedChar, File: "", Line: s.line, Col: s.col, Msg: "Unexpected character."}
+			return &LoxError{Number: UnexpectedChar, File: "", Line: s.startLine, Col: s.startCol, Msg: "Unexpected character."}
 		}
 	}
 
@@ -145,63 +282,218 @@ func (s *Scanner) scanToken() error {
 	return nil
 }
 
+// skipBlockComment skips the rest of a `/* ... */` comment. Block comments
+// nest, so every `/*` inside it needs its own `*/`.
+func (s *Scanner) skipBlockComment() error {
+	depth := 1
+
+	for depth > 0 {
+		if s.isAtEnd() {
+			return &LoxError{Number: UnterminatedComment, File: "", Line: s.startLine, Col: s.startCol, Msg: "Unterminated block comment. Expected */"}
+		}
+
+		b := s.readNext()
+
+		if b == '/' && s.match('*') {
+			depth++
+		} else if b == '*' && s.match('/') {
+			depth--
+		}
+	}
+
+	return nil
+}
+
+// parseString scans the body of a string literal up to either the closing
+// quote or the `${` starting an interpolated expression.
 func (s *Scanner) parseString() error {
+	sb := strings.Builder{}
+
 	for !s.isAtEnd() {
 		b := s.peek()
 
-		if b == '"' {
-			break
-		}
+		switch {
+		case b == '"':
+			s.readNext()
+			s.addToken(STRING, sb.String())
 
-		s.readNext()
+			return nil
+		case b == '$' && s.peekNext() == '{':
+			s.readNext()
+			s.readNext()
+			s.interpolations = append(s.interpolations, 0)
+			s.addToken(INTERPOLATION, sb.String())
+
+			return nil
+		case b == '\\':
+			r, err := s.parseEscape()
+			if err != nil {
+				return err
+			}
+
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(s.readNext())
+		}
 	}
 
+	return &LoxError{Number: UnterminatedString, File: "", Line: s.startLine, Col: s.startCol, Msg: "Unterminated string. Expected \""}
+}
+
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'\\': '\\',
+	'$':  '$',
+}
+
+func (s *Scanner) parseEscape() (rune, error) {
+	s.readNext()
+	line, col := s.line, s.col
+
 	if s.isAtEnd() {
-		return &LoxError{Number: UnterminatedString, File: "", Line: s.line, Col: s.col, Msg: "Unterminated string. Expected \""}
+		return 0, &LoxError{Number: UnterminatedString, File: "", Line: s.startLine, Col: s.startCol, Msg: "Unterminated string. Expected \""}
+	}
+
+	b := s.readNext()
+
+	if r, ok := escapes[b]; ok {
+		return r, nil
+	}
+
+	if b != 'u' {
+		return 0, &LoxError{Number: InvalidEscape, File: "", Line: line, Col: col, Msg: fmt.Sprintf("Invalid escape sequence '\\%c'.", b)}
+	}
+
+	if s.isAtEnd() || s.peek() != '{' {
+		return 0, &LoxError{Number: InvalidEscape, File: "", Line: line, Col: col, Msg: "Expected '{' after '\\u'."}
 	}
 
 	s.readNext()
 
-	val := s.curTokenSb.String()
+	hex := strings.Builder{}
+	for !s.isAtEnd() && s.peek() != '}' && s.peek() != '"' {
+		hex.WriteRune(s.readNext())
+	}
 
-	s.addToken(STRING, val[1:len(val)-1])
+	if s.isAtEnd() || s.peek() != '}' {
+		return 0, &LoxError{Number: InvalidEscape, File: "", Line: line, Col: col, Msg: "Expected '}' after unicode escape."}
+	}
 
-	return nil
+	s.readNext()
+
+	code, err := strconv.ParseUint(hex.String(), 16, 32)
+	if err != nil || hex.Len() > 6 || !utf8.ValidRune(rune(code)) {
+		return 0, &LoxError{Number: InvalidEscape, File: "", Line: line, Col: col, Msg: fmt.Sprintf("Invalid unicode code point '%s'.", hex.String())}
+	}
+
+	return rune(code), nil
 }
 
-func (s *Scanner) parseNumber() error {
-	for !s.isAtEnd() {
-		b := s.peek()
+// parseNumber scans a number literal: an integer in decimal, in hexadecimal
+// with the prefix 0x or in binary with the prefix 0b, or a decimal number with
+// a fractional part. Digits can be separated by single underscores, as in
+// 1_000_000. Integers that do not fit in an int64 become *big.Int literals.
+func (s *Scanner) parseNumber(first rune) error {
+	base := 10
+
+	if first == '0' && (s.peek() == 'x' || s.peek() == 'X') {
+		base = 16
+	} else if first == '0' && (s.peek() == 'b' || s.peek() == 'B') {
+		base = 2
+	}
 
-		if !isDigit(b) {
-			break
+	digits := strings.Builder{}
+	if base == 10 {
+		digits.WriteRune(first)
+	} else {
+		s.readNext()
+	}
+
+	if err := s.scanDigits(&digits, base, base == 10); err != nil {
+		return err
+	}
+
+	isFloat := false
+	if base == 10 && s.peek() == '.' && isDigit(s.peekNext()) {
+		isFloat = true
+		digits.WriteRune(s.readNext())
+
+		if err := s.scanDigits(&digits, base, false); err != nil {
+			return err
 		}
+	}
 
-		s.readNext()
+	if isAlpha(s.peek()) || isDigit(s.peek()) {
+		return s.invalidNumber()
 	}
 
-	if !s.isAtEnd() && s.peek() == '.' && isDigit(s.peekNext()) {
-		s.readNext()
+	if isFloat {
+		val, err := strconv.ParseFloat(digits.String(), 64)
+		if err != nil {
+			return s.invalidNumber()
+		}
 
-		for !s.isAtEnd() {
-			b := s.peek()
+		s.addToken(NUMBER, val)
 
-			if !isDigit(b) {
-				break
+		return nil
+	}
+
+	if val, err := strconv.ParseInt(digits.String(), base, 64); err == nil {
+		s.addToken(NUMBER, val)
+
+		return nil
+	}
+
+	val, ok := new(big.Int).SetString(digits.String(), base)
+	if !ok {
+		return s.invalidNumber()
+	}
+
+	s.addToken(NUMBER, val)
+
+	return nil
+}
+
+// scanDigits appends the digits in base that follow to sb, dropping the
+// underscores between them. started tells whether a digit has already been
+// scanned.
+func (s *Scanner) scanDigits(sb *strings.Builder, base int, started bool) error {
+	for {
+		b := s.peek()
+
+		if b == '_' {
+			if !started || !isDigitIn(s.peekNext(), base) {
+				return s.invalidNumber()
 			}
 
 			s.readNext()
+
+			continue
 		}
-	}
 
-	val, err := strconv.ParseFloat(s.curTokenSb.String(), 64)
-	fatal(err)
+		if !isDigitIn(b, base) {
+			break
+		}
 
-	s.addToken(NUMBER, val)
+		sb.WriteRune(s.readNext())
+		started = true
+	}
+
+	if !started {
+		return s.invalidNumber()
+	}
 
 	return nil
 }
 
+func (s *Scanner) invalidNumber() error {
+	return &LoxError{Number: InvalidNumber, File: "", Line: s.startLine, Col: s.startCol, Msg: "Invalid number literal."}
+}
+
 func (s *Scanner) parseIdentifier() {
 	for !s.isAtEnd() && isAlphaNumeric(s.peek()) {
 		s.readNext()
@@ -216,83 +508,66 @@ func (s *Scanner) parseIdentifier() {
 	}
 }
 
-func (s *Scanner) readNext() byte {
-	b, err := s.source.ReadByte()
+func (s *Scanner) readNext() rune {
+	r, size, err := s.source.ReadRune()
+	s.runeSize = size
 
-	if err == io.EOF {
-		err = nil
-	} else {
-		fatal(err)
+	// A failing reader ends the input like EOF does, and Err reports why.
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
 	}
 
-	s.curTokenSb.WriteByte(b)
+	s.curTokenSb.WriteRune(r)
 
 	s.col += 1
 
-	if b == '\n' {
+	if r == '\n' {
 		s.col = 0
 		s.line++
 	}
 
-	return b
+	return r
 }
 
-func (s *Scanner) peek() byte {
-	b, err := s.source.ReadByte()
-	fatal(err)
-	err = s.source.UnreadByte()
-	fatal(err)
-	return b
+func (s *Scanner) peek() rune {
+	return s.peekAt(0)
 }
 
-func (s *Scanner) peekNext() byte {
-	if s.source.Len() < 2 {
-		return 0
-	}
-
-	_, err := s.source.ReadByte()
-	fatal(err)
-
-	b, err := s.source.ReadByte()
-	fatal(err)
-
-	err = s.source.UnreadByte()
-	fatal(err)
-
-	err = s.source.UnreadByte()
-	fatal(err)
-	return b
+func (s *Scanner) peekNext() rune {
+	return s.peekAt(1)
 }
 
-func (s *Scanner) match(m string) bool {
-	sb := strings.Builder{}
+// peekAt returns the n-th rune after the current one without consuming
+// anything, or 0 if the input ends before it.
+func (s *Scanner) peekAt(n int) rune {
+	bs, _ := s.source.Peek((n + 1) * utf8.UTFMax)
 
-	line := s.line
-	col := s.col
+	for i := 0; ; i++ {
+		r, size := utf8.DecodeRune(bs)
+		if size == 0 {
+			return 0
+		}
 
-	for sb.Len() < len(m) && !s.isAtEnd() {
-		b := s.readNext()
+		if i == n {
+			return r
+		}
 
-		sb.WriteByte(b)
+		bs = bs[size:]
 	}
+}
 
-	if sb.String() != m {
-		s.unread(sb.Len(), line, col)
-
+func (s *Scanner) match(r rune) bool {
+	if s.isAtEnd() || s.peek() != r {
 		return false
 	}
 
-	return true
-}
+	s.readNext()
 
-func (s *Scanner) unread(n, line, col int) {
-	s.source.Seek(-int64(n), io.SeekCurrent)
-	s.line = line
-	s.col = col
+	return true
 }
 
 func (s *Scanner) addToken(t TokenType, literal interface{}) {
-	token := Token{Type: t, Lexeme: s.curTokenSb.String(), Literal: literal, File: "file.lox", Line: s.line, Col: s.col}
+	token := Token{Type: t, Lexeme: s.curTokenSb.String(), Literal: literal, File: "file.lox", Line: s.startLine, Col: s.startCol}
 
 	s.tokens = append(s.tokens, token)
 	s.curTokenSb.Reset()
@@ -0,0 +1,181 @@
+package golox_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/agayev169/golox"
+)
+
+type scannerTestDto struct {
+	Source   string
+	Expected []golox.Token
+	Error    *golox.LoxError
+}
+
+var scannerTestData = map[string]scannerTestDto{
+	"escapes": {
+		Source: `"a\n\t\"\\\u{48}\u{1F600}"`,
+		Expected: []golox.Token{
+			{Type: golox.STRING, Literal: "a\n\t\"\\H\U0001F600", Line: 1, Col: 1},
+			{Type: golox.EOF},
+		},
+	},
+	"interpolation": {
+		Source: `"a ${b} c"`,
+		Expected: []golox.Token{
+			{Type: golox.INTERPOLATION, Literal: "a ", Line: 1, Col: 1},
+			{Type: golox.IDENTIFIER, Lexeme: "b", Line: 1, Col: 6},
+			{Type: golox.STRING, Literal: " c", Line: 1, Col: 7},
+			{Type: golox.EOF},
+		},
+	},
+	"multiline": {
+		Source: "\"a\nb\" c",
+		Expected: []golox.Token{
+			{Type: golox.STRING, Literal: "a\nb", Line: 1, Col: 1},
+			{Type: golox.IDENTIFIER, Lexeme: "c", Line: 2, Col: 4},
+			{Type: golox.EOF},
+		},
+	},
+	"unicode": {
+		Source: `"日本" ñandú`,
+		Expected: []golox.Token{
+			{Type: golox.STRING, Literal: "日本", Line: 1, Col: 1},
+			{Type: golox.IDENTIFIER, Lexeme: "ñandú", Line: 1, Col: 6},
+			{Type: golox.EOF},
+		},
+	},
+	"unicode error column": {
+		Source: "\"ü\" # x",
+		Error:  &golox.LoxError{Number: golox.UnexpectedChar, Line: 1, Col: 5},
+	},
+	"invalid escape": {
+		Source: "var s =\n  \"ab\\q\";",
+		Error:  &golox.LoxError{Number: golox.InvalidEscape, Line: 2, Col: 6},
+	},
+	"invalid code point": {
+		Source: `"\u{110000}"`,
+		Error:  &golox.LoxError{Number: golox.InvalidEscape, Line: 1, Col: 2},
+	},
+	"compound operators": {
+		Source: "a+=b++%c--?d:e",
+		Expected: []golox.Token{
+			{Type: golox.IDENTIFIER, Lexeme: "a"},
+			{Type: golox.PLUS_EQUAL, Lexeme: "+="},
+			{Type: golox.IDENTIFIER, Lexeme: "b"},
+			{Type: golox.PLUS_PLUS, Lexeme: "++"},
+			{Type: golox.PERCENT, Lexeme: "%"},
+			{Type: golox.IDENTIFIER, Lexeme: "c"},
+			{Type: golox.MINUS_MINUS, Lexeme: "--"},
+			{Type: golox.QUESTION, Lexeme: "?"},
+			{Type: golox.IDENTIFIER, Lexeme: "d"},
+			{Type: golox.COLON, Lexeme: ":"},
+			{Type: golox.IDENTIFIER, Lexeme: "e"},
+			{Type: golox.EOF},
+		},
+	},
+	"block comments": {
+		Source: "a /* x\n/* ü */\n*/ b",
+		Expected: []golox.Token{
+			{Type: golox.IDENTIFIER, Lexeme: "a", Line: 1, Col: 1},
+			{Type: golox.IDENTIFIER, Lexeme: "b", Line: 3, Col: 4},
+			{Type: golox.EOF},
+		},
+	},
+	"unterminated block comment": {
+		Source: "a\n  /* /* */",
+		Error:  &golox.LoxError{Number: golox.UnterminatedComment, Line: 2, Col: 3},
+	},
+	"numbers": {
+		Source: "12 0x1F 0b101 1_000 2.5",
+		Expected: []golox.Token{
+			{Type: golox.NUMBER, Literal: int64(12)},
+			{Type: golox.NUMBER, Literal: int64(31)},
+			{Type: golox.NUMBER, Literal: int64(5)},
+			{Type: golox.NUMBER, Literal: int64(1000)},
+			{Type: golox.NUMBER, Literal: 2.5},
+			{Type: golox.EOF},
+		},
+	},
+	"invalid number": {
+		Source: "x = 0b12",
+		Error:  &golox.LoxError{Number: golox.InvalidNumber, Line: 1, Col: 5},
+	},
+	"unterminated interpolation": {
+		Source: `"a ${b"`,
+		Error:  &golox.LoxError{Number: golox.UnterminatedString, Line: 1, Col: 7},
+	},
+}
+
+func TestScanner(t *testing.T) {
+	for k, tv := range scannerTestData {
+		actual, err := golox.NewScanner(bytes.NewReader([]byte(tv.Source))).ScanTokens()
+
+		if tv.Error != nil {
+			lerr, ok := err.(*golox.LoxError)
+			if !ok || lerr.Number != tv.Error.Number || lerr.Line != tv.Error.Line || lerr.Col != tv.Error.Col {
+				t.Fatalf("Failed on test %s. Got error %v, expected error %v", k, err, tv.Error)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Failed on test %s. Got error on s.ScanTokens(): %v", k, err)
+		}
+
+		if len(actual) != len(tv.Expected) {
+			t.Fatalf("Failed on test %s. Expected %d tokens, got %d", k, len(tv.Expected), len(actual))
+		}
+
+		for i, e := range tv.Expected {
+			a := actual[i]
+			if a.Type != e.Type || (e.Literal != nil && a.Literal != e.Literal) ||
+				(e.Lexeme != "" && a.Lexeme != e.Lexeme) || (e.Line != 0 && (a.Line != e.Line || a.Col != e.Col)) {
+				t.Fatalf("Failed on test %s. Token %d: expected %+v, got %+v", k, i, e, a)
+			}
+		}
+	}
+}
+
+// TestScannerReplacementCharacter checks that only invalid encodings are
+// reported as such, not a correctly encoded U+FFFD.
+func TestScannerReplacementCharacter(t *testing.T) {
+	for src, msg := range map[string]string{
+		"\xff":     "Invalid UTF-8 encoding.",
+		"\uFFFD":   "Unexpected character.",
+		"a\xffb":   "Invalid UTF-8 encoding.",
+		"a \uFFFD": "Unexpected character.",
+	} {
+		_, err := golox.NewScanner(bytes.NewReader([]byte(src))).ScanTokens()
+
+		lerr, ok := err.(*golox.LoxError)
+		if !ok || lerr.Number != golox.UnexpectedChar || lerr.Msg != msg {
+			t.Errorf("%q: expected the error %q, got %v", src, msg, err)
+		}
+	}
+
+	tokens, err := golox.NewScanner(bytes.NewReader([]byte("\"\uFFFD\""))).ScanTokens()
+	if err != nil || len(tokens) != 2 || tokens[0].Literal != "\uFFFD" {
+		t.Errorf("Expected a string holding U+FFFD, got %v, %v", tokens, err)
+	}
+}
+
+// TestScannerReaderError checks that an error of the underlying reader is
+// returned rather than the errors the truncated input causes.
+func TestScannerReaderError(t *testing.T) {
+	errRead := errors.New("connection reset")
+
+	for _, src := range []string{"", "var a = 1;", `print "abc`} {
+		r := io.MultiReader(strings.NewReader(src), iotest.ErrReader(errRead))
+
+		if _, err := golox.NewScanner(r).ScanTokens(); !errors.Is(err, errRead) {
+			t.Errorf("%q: expected the read error, got %v", src, err)
+		}
+	}
+}
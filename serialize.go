@@ -0,0 +1,771 @@
+package golox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// The binary format of the programs WriteProgram saves, so that scripts can
+// be run without scanning, parsing and resolving them again:
+//
+//	magic           "GLOXPROG"
+//	version         programVersion
+//	source hash     the SHA-256 of the source, see SourceHash
+//	statements      count, then each statement
+//	locals          count, then the ID, depth and slot of each local
+//	tail calls      count, then the ID of each return of a tail call
+//
+// A node is its kind followed by its fields, the kind being 0 if the node is
+// missing. Every Stmt and Expr is numbered in the order it is written: the
+// resolution data the Interpreter keys with node pointers refers to nodes by
+// these IDs instead. Integers are varints, and strings are either 0 followed
+// by the length and the bytes of a new string or the position of an earlier
+// one plus 1, since lexemes and file names repeat a lot.
+const (
+	programMagic   = "GLOXPROG"
+	programVersion = 1
+)
+
+// ErrStaleProgram is returned by ReadProgram when the program was saved from
+// another version of the source.
+var ErrStaleProgram = errors.New("the program was compiled from a different source")
+
+// The kinds of nodes.
+const (
+	nodeNil = iota
+	nodeBlock
+	nodeExpression
+	nodePrint
+	nodeVar
+	nodeFunc
+	nodeIf
+	nodeWhile
+	nodeReturn
+	nodeThrow
+	nodeTry
+	nodeAssign
+	nodeBinary
+	nodeGrouping
+	nodeLiteral
+	nodeUnary
+	nodeCall
+	nodeVariable
+	nodeLogical
+	nodeLambda
+	nodeGet
+	nodeConditional
+	nodeCompoundAssign
+	nodeIncrement
+)
+
+// SourceHash returns the hash a saved program records of its source.
+func SourceHash(src []byte) [sha256.Size]byte {
+	return sha256.Sum256(src)
+}
+
+// WriteProgram saves stmts, resolved with interp, and the resolution data to
+// w. hash is the SourceHash of their source.
+func WriteProgram(w io.Writer, hash [sha256.Size]byte, stmts []Stmt, interp *Interpreter) error {
+	e := &programEncoder{interp: interp, strs: make(map[string]int)}
+
+	e.buf.WriteString(programMagic)
+	e.uint(programVersion)
+	e.buf.Write(hash[:])
+
+	e.stmts(stmts)
+
+	e.uint(uint64(len(e.locals)))
+	for _, l := range e.locals {
+		e.uint(uint64(l.id))
+		e.uint(uint64(l.depth))
+		e.uint(uint64(l.slot))
+	}
+
+	e.uint(uint64(len(e.tailCalls)))
+	for _, id := range e.tailCalls {
+		e.uint(uint64(id))
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+
+	_, err := w.Write(e.buf.Bytes())
+
+	return err
+}
+
+// ReadProgram loads the statements saved by WriteProgram from r, adding their
+// resolution data to interp. It returns ErrStaleProgram if the program was
+// not saved from the source with the given hash.
+func ReadProgram(r io.Reader, hash [sha256.Size]byte, interp *Interpreter) ([]Stmt, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &programDecoder{data: data}
+
+	if string(d.bytes(len(programMagic))) != programMagic {
+		return nil, errors.New("not a compiled Lox program")
+	}
+
+	if v := d.uint(); v != programVersion {
+		return nil, fmt.Errorf("unsupported version %d of compiled programs", v)
+	}
+
+	if h := d.bytes(sha256.Size); d.err == nil && !bytes.Equal(h, hash[:]) {
+		return nil, ErrStaleProgram
+	}
+
+	stmts := d.stmts()
+
+	locals := make(map[Expr]local)
+	for i, n := 0, d.count(); i < n; i++ {
+		e, _ := d.node(d.uint()).(Expr)
+		l := local{depth: int(d.uint()), slot: int(d.uint())}
+
+		if e == nil && d.err == nil {
+			d.err = errors.New("invalid local variable")
+		}
+
+		locals[e] = l
+	}
+
+	tailCalls := make([]*Return, 0)
+	for i, n := 0, d.count(); i < n; i++ {
+		ret, ok := d.node(d.uint()).(*Return)
+		if !ok && d.err == nil {
+			d.err = errors.New("invalid tail call")
+		}
+
+		tailCalls = append(tailCalls, ret)
+	}
+
+	if d.err == nil && d.pos != len(d.data) {
+		d.err = errors.New("unexpected data after the program")
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("corrupted compiled program: %w", d.err)
+	}
+
+	for e, l := range locals {
+		interp.Resolve(e, l.depth, l.slot)
+	}
+
+	for _, ret := range tailCalls {
+		interp.resolveTailCall(ret)
+	}
+
+	return stmts, nil
+}
+
+// Encoding
+
+type programEncoder struct {
+	interp *Interpreter
+	buf    bytes.Buffer
+	// The ID of the next node.
+	id   int
+	strs map[string]int
+	// The resolution data of the nodes written.
+	locals    []programLocal
+	tailCalls []int
+	err       error
+}
+
+type programLocal struct {
+	id, depth, slot int
+}
+
+func (e *programEncoder) stmts(ss []Stmt) {
+	e.uint(uint64(len(ss)))
+
+	for _, s := range ss {
+		e.stmt(s)
+	}
+}
+
+func (e *programEncoder) stmt(s Stmt) {
+	if s == nil {
+		e.uint(nodeNil)
+
+		return
+	}
+
+	id := e.id
+	e.id++
+
+	switch st := s.(type) {
+	case *Block:
+		e.uint(nodeBlock)
+		e.stmts(st.Stmts)
+	case *Expression:
+		e.uint(nodeExpression)
+		e.expr(st.Expr)
+	case *Print:
+		e.uint(nodePrint)
+		e.token(st.Keyword)
+		e.expr(st.Expr)
+	case *Var:
+		e.uint(nodeVar)
+		e.token(st.Name)
+		e.typeExpr(st.Type)
+		e.expr(st.Initializer)
+	case *Func:
+		e.uint(nodeFunc)
+		e.function(st)
+	case *If:
+		e.uint(nodeIf)
+		e.token(st.Keyword)
+		e.expr(st.Condition)
+		e.stmt(st.Body)
+		e.stmt(st.ElseBody)
+	case *While:
+		e.uint(nodeWhile)
+		e.token(st.Keyword)
+		e.expr(st.Condition)
+		e.stmt(st.Body)
+	case *Return:
+		e.uint(nodeReturn)
+		e.token(st.Keyword)
+		e.expr(st.Value)
+
+		if e.interp.tailCalls[st] {
+			e.tailCalls = append(e.tailCalls, id)
+		}
+	case *Throw:
+		e.uint(nodeThrow)
+		e.token(st.Keyword)
+		e.expr(st.Value)
+	case *Try:
+		e.uint(nodeTry)
+		e.token(st.Keyword)
+		e.stmts(st.Body)
+		e.token(st.CatchName)
+		e.optStmts(st.CatchBody)
+		e.optStmts(st.FinallyBody)
+	default:
+		e.fail(fmt.Errorf("cannot save a statement of type %T", s))
+	}
+}
+
+// optStmts writes statements that may be missing, as opposed to empty.
+func (e *programEncoder) optStmts(ss []Stmt) {
+	e.bool(ss != nil)
+
+	if ss != nil {
+		e.stmts(ss)
+	}
+}
+
+// function writes the fields of a function declaration, without its ID since
+// lambdas own theirs.
+func (e *programEncoder) function(f *Func) {
+	e.token(f.Name)
+
+	e.uint(uint64(len(f.Params)))
+	for _, p := range f.Params {
+		e.token(p)
+	}
+
+	e.bool(f.ParamTypes != nil)
+	if f.ParamTypes != nil {
+		e.typeExprs(f.ParamTypes)
+	}
+
+	e.typeExpr(f.ReturnType)
+	e.stmts(f.Body)
+}
+
+func (e *programEncoder) expr(x Expr) {
+	if x == nil {
+		e.uint(nodeNil)
+
+		return
+	}
+
+	if l, ok := e.interp.locals[x]; ok {
+		e.locals = append(e.locals, programLocal{id: e.id, depth: l.depth, slot: l.slot})
+	}
+
+	e.id++
+
+	switch ex := x.(type) {
+	case *Assign:
+		e.uint(nodeAssign)
+		e.token(ex.Name)
+		e.expr(ex.Value)
+	case *Binary:
+		e.uint(nodeBinary)
+		e.expr(ex.Left)
+		e.token(ex.Operator)
+		e.expr(ex.Right)
+	case *Grouping:
+		e.uint(nodeGrouping)
+		e.expr(ex.Expr)
+	case *Literal:
+		e.uint(nodeLiteral)
+		e.value(ex.Value)
+	case *Unary:
+		e.uint(nodeUnary)
+		e.token(ex.Operator)
+		e.expr(ex.Right)
+	case *Call:
+		e.uint(nodeCall)
+		e.expr(ex.Callee)
+		e.token(ex.Paren)
+		e.uint(uint64(len(ex.Args)))
+		for _, arg := range ex.Args {
+			e.expr(arg)
+		}
+	case *Variable:
+		e.uint(nodeVariable)
+		e.token(ex.Name)
+	case *Logical:
+		e.uint(nodeLogical)
+		e.expr(ex.Left)
+		e.token(ex.Operator)
+		e.expr(ex.Right)
+	case *Lambda:
+		e.uint(nodeLambda)
+		e.function(ex.Decl)
+	case *Get:
+		e.uint(nodeGet)
+		e.expr(ex.Object)
+		e.token(ex.Name)
+	case *Conditional:
+		e.uint(nodeConditional)
+		e.expr(ex.Condition)
+		e.token(ex.Question)
+		e.expr(ex.Then)
+		e.expr(ex.Else)
+	case *CompoundAssign:
+		e.uint(nodeCompoundAssign)
+		e.token(ex.Name)
+		e.token(ex.Operator)
+		e.expr(ex.Value)
+	case *Increment:
+		e.uint(nodeIncrement)
+		e.token(ex.Name)
+		e.token(ex.Operator)
+		e.bool(ex.Postfix)
+	default:
+		e.fail(fmt.Errorf("cannot save an expression of type %T", x))
+	}
+}
+
+func (e *programEncoder) typeExpr(t *TypeExpr) {
+	e.bool(t != nil)
+	if t == nil {
+		return
+	}
+
+	e.token(t.Name)
+
+	e.bool(t.Params != nil)
+	if t.Params != nil {
+		e.typeExprs(t.Params)
+	}
+
+	e.typeExpr(t.Return)
+}
+
+func (e *programEncoder) typeExprs(ts []*TypeExpr) {
+	e.uint(uint64(len(ts)))
+
+	for _, t := range ts {
+		e.typeExpr(t)
+	}
+}
+
+// token writes all the fields of t but its literal, which only the parser
+// uses.
+func (e *programEncoder) token(t Token) {
+	e.uint(uint64(t.Type))
+	e.str(t.Lexeme)
+	e.str(t.File)
+	e.int(int64(t.Line))
+	e.int(int64(t.Col))
+}
+
+func (e *programEncoder) value(v Value) {
+	e.uint(uint64(v.Type()))
+
+	switch v.Type() {
+	case NilType:
+	case BoolType:
+		e.bool(v.AsBool())
+	case NumberType:
+		e.uint(math.Float64bits(v.AsNumber()))
+	case IntType:
+		e.bool(v.IsBigInt())
+		if v.IsBigInt() {
+			b, _ := v.AsBigInt().GobEncode()
+			e.str(string(b))
+		} else {
+			e.int(v.AsInt())
+		}
+	case StringType:
+		e.str(v.AsString())
+	default:
+		e.fail(fmt.Errorf("cannot save a literal of type %s", v.Type()))
+	}
+}
+
+func (e *programEncoder) str(s string) {
+	if i, ok := e.strs[s]; ok {
+		e.uint(uint64(i + 1))
+
+		return
+	}
+
+	e.strs[s] = len(e.strs)
+	e.uint(0)
+	e.uint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *programEncoder) bool(b bool) {
+	if b {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *programEncoder) uint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+
+	e.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (e *programEncoder) int(v int64) {
+	var b [binary.MaxVarintLen64]byte
+
+	e.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (e *programEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// Decoding
+
+// programDecoder reads a program from data. After the first error, which it
+// keeps in err, it returns zero values.
+type programDecoder struct {
+	data []byte
+	pos  int
+	// The nodes read, by ID.
+	nodes []interface{}
+	strs  []string
+	err   error
+}
+
+func (d *programDecoder) stmts() []Stmt {
+	n := d.count()
+	res := make([]Stmt, 0, n)
+
+	for i := 0; i < n && d.err == nil; i++ {
+		res = append(res, d.stmt())
+	}
+
+	return res
+}
+
+func (d *programDecoder) stmt() Stmt {
+	kind := d.uint()
+	if kind == nodeNil || d.err != nil {
+		return nil
+	}
+
+	id := len(d.nodes)
+	d.nodes = append(d.nodes, nil)
+
+	var s Stmt
+
+	switch kind {
+	case nodeBlock:
+		s = &Block{Stmts: d.stmts()}
+	case nodeExpression:
+		s = &Expression{Expr: d.expr()}
+	case nodePrint:
+		s = &Print{Keyword: d.token(), Expr: d.expr()}
+	case nodeVar:
+		s = &Var{Name: d.token(), Type: d.typeExpr(), Initializer: d.expr()}
+	case nodeFunc:
+		s = d.function()
+	case nodeIf:
+		s = &If{Keyword: d.token(), Condition: d.expr(), Body: d.stmt(), ElseBody: d.stmt()}
+	case nodeWhile:
+		s = &While{Keyword: d.token(), Condition: d.expr(), Body: d.stmt()}
+	case nodeReturn:
+		s = &Return{Keyword: d.token(), Value: d.expr()}
+	case nodeThrow:
+		s = &Throw{Keyword: d.token(), Value: d.expr()}
+	case nodeTry:
+		s = &Try{Keyword: d.token(), Body: d.stmts(), CatchName: d.token(), CatchBody: d.optStmts(), FinallyBody: d.optStmts()}
+	default:
+		d.fail(fmt.Errorf("invalid statement kind %d", kind))
+	}
+
+	d.nodes[id] = s
+
+	return s
+}
+
+func (d *programDecoder) optStmts() []Stmt {
+	if !d.bool() {
+		return nil
+	}
+
+	return d.stmts()
+}
+
+func (d *programDecoder) function() *Func {
+	f := &Func{Name: d.token()}
+
+	n := d.count()
+	f.Params = make([]Token, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		f.Params = append(f.Params, d.token())
+	}
+
+	if d.bool() {
+		f.ParamTypes = d.typeExprs()
+	}
+
+	f.ReturnType = d.typeExpr()
+	f.Body = d.stmts()
+
+	return f
+}
+
+func (d *programDecoder) expr() Expr {
+	kind := d.uint()
+	if kind == nodeNil || d.err != nil {
+		return nil
+	}
+
+	id := len(d.nodes)
+	d.nodes = append(d.nodes, nil)
+
+	var x Expr
+
+	switch kind {
+	case nodeAssign:
+		x = &Assign{Name: d.token(), Value: d.expr()}
+	case nodeBinary:
+		x = &Binary{Left: d.expr(), Operator: d.token(), Right: d.expr()}
+	case nodeGrouping:
+		x = &Grouping{Expr: d.expr()}
+	case nodeLiteral:
+		x = &Literal{Value: d.value()}
+	case nodeUnary:
+		x = &Unary{Operator: d.token(), Right: d.expr()}
+	case nodeCall:
+		c := &Call{Callee: d.expr(), Paren: d.token()}
+
+		n := d.count()
+		c.Args = make([]Expr, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			c.Args = append(c.Args, d.expr())
+		}
+
+		x = c
+	case nodeVariable:
+		x = &Variable{Name: d.token()}
+	case nodeLogical:
+		x = &Logical{Left: d.expr(), Operator: d.token(), Right: d.expr()}
+	case nodeLambda:
+		x = &Lambda{Decl: d.function()}
+	case nodeGet:
+		x = &Get{Object: d.expr(), Name: d.token()}
+	case nodeConditional:
+		x = &Conditional{Condition: d.expr(), Question: d.token(), Then: d.expr(), Else: d.expr()}
+	case nodeCompoundAssign:
+		x = &CompoundAssign{Name: d.token(), Operator: d.token(), Value: d.expr()}
+	case nodeIncrement:
+		x = &Increment{Name: d.token(), Operator: d.token(), Postfix: d.bool()}
+	default:
+		d.fail(fmt.Errorf("invalid expression kind %d", kind))
+	}
+
+	d.nodes[id] = x
+
+	return x
+}
+
+// node returns the node with the given ID.
+func (d *programDecoder) node(id uint64) interface{} {
+	if id >= uint64(len(d.nodes)) {
+		d.fail(fmt.Errorf("invalid node %d", id))
+
+		return nil
+	}
+
+	return d.nodes[id]
+}
+
+func (d *programDecoder) typeExpr() *TypeExpr {
+	if !d.bool() {
+		return nil
+	}
+
+	t := &TypeExpr{Name: d.token()}
+	if d.bool() {
+		t.Params = d.typeExprs()
+	}
+
+	t.Return = d.typeExpr()
+
+	return t
+}
+
+func (d *programDecoder) typeExprs() []*TypeExpr {
+	n := d.count()
+	res := make([]*TypeExpr, 0, n)
+
+	for i := 0; i < n && d.err == nil; i++ {
+		res = append(res, d.typeExpr())
+	}
+
+	return res
+}
+
+func (d *programDecoder) token() Token {
+	return Token{Type: TokenType(d.uint()), Lexeme: d.str(), File: d.str(), Line: int(d.int()), Col: int(d.int())}
+}
+
+func (d *programDecoder) value() Value {
+	switch typ := ValueType(d.uint()); typ {
+	case NilType:
+		return NilValue
+	case BoolType:
+		return BoolValue(d.bool())
+	case NumberType:
+		return NumberValue(math.Float64frombits(d.uint()))
+	case IntType:
+		if !d.bool() {
+			return IntValue(d.int())
+		}
+
+		b := new(big.Int)
+		if err := b.GobDecode([]byte(d.str())); err != nil {
+			d.fail(err)
+		}
+
+		return BigIntValue(b)
+	case StringType:
+		return StringValue(d.str())
+	default:
+		d.fail(fmt.Errorf("invalid literal type %d", typ))
+	}
+
+	return NilValue
+}
+
+func (d *programDecoder) str() string {
+	i := d.uint()
+	if i == 0 {
+		s := string(d.bytes(d.count()))
+		if d.err == nil {
+			d.strs = append(d.strs, s)
+		}
+
+		return s
+	}
+
+	if i > uint64(len(d.strs)) {
+		d.fail(fmt.Errorf("invalid string %d", i))
+
+		return ""
+	}
+
+	return d.strs[i-1]
+}
+
+func (d *programDecoder) bool() bool {
+	b := d.bytes(1)
+
+	return len(b) == 1 && b[0] != 0
+}
+
+func (d *programDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n > len(d.data)-d.pos {
+		d.fail(io.ErrUnexpectedEOF)
+
+		return nil
+	}
+
+	d.pos += n
+
+	return d.data[d.pos-n : d.pos]
+}
+
+// count reads the length of a list, which cannot exceed the data left since
+// every element takes at least a byte.
+func (d *programDecoder) count() int {
+	n := d.uint()
+	if n > uint64(len(d.data)-d.pos) {
+		d.fail(fmt.Errorf("invalid length %d", n))
+
+		return 0
+	}
+
+	return int(n)
+}
+
+func (d *programDecoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+
+		return 0
+	}
+
+	d.pos += n
+
+	return v
+}
+
+func (d *programDecoder) int() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+
+		return 0
+	}
+
+	d.pos += n
+
+	return v
+}
+
+func (d *programDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
@@ -0,0 +1,202 @@
+package golox_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+// TestProgramRoundTrip saves and reloads every conformance test and checks
+// that the reloaded program prints the same tree and behaves like the source.
+func TestProgramRoundTrip(t *testing.T) {
+	forEachConformanceFile(t, func(t *testing.T, path string, src []byte) {
+		stmts, interp, lerr := parseAndResolve(src)
+		if lerr != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := golox.ReadProgram(&buf, golox.SourceHash(src), golox.NewInterpreter())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exp, act := printStmts(stmts), printStmts(loaded); exp != act {
+			t.Errorf("Expected the tree\n%s\nbut got\n%s", exp, act)
+		}
+
+		res := golox.RunTestSourceWith(path, src, func(src []byte, out *bytes.Buffer) (compileErr, runtimeErr *golox.LoxError) {
+			var buf bytes.Buffer
+			if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+				t.Fatal(err)
+			}
+
+			interp := golox.NewInterpreter()
+			interp.SetOutput(out)
+
+			loaded, err := golox.ReadProgram(&buf, golox.SourceHash(src), interp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, lerr := interp.Interpret(golox.NewOptimizer().Optimize(loaded))
+
+			return nil, lerr
+		})
+
+		for _, f := range res.Failures {
+			t.Error(f)
+		}
+	})
+}
+
+func TestProgramStale(t *testing.T) {
+	src := []byte("var a = 1;\n{ var b = a; print b; }\n")
+
+	stmts, interp, lerr := parseAndResolve(src)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var buf bytes.Buffer
+	if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := golox.ReadProgram(&buf, golox.SourceHash(append(src, '\n')), golox.NewInterpreter())
+	if !errors.Is(err, golox.ErrStaleProgram) {
+		t.Errorf("Expected a stale program, got %v", err)
+	}
+}
+
+// TestProgramCorrupted checks that every truncation of a saved program is
+// reported rather than loaded or panicking.
+func TestProgramCorrupted(t *testing.T) {
+	src := []byte("fun f(a: num): num { var b = a; return f(b - 1); }\ntry { print 1.5 + 100000000000000000000 + \"s\"; } catch (e) { print e; }\n")
+
+	stmts, interp, lerr := parseAndResolve(src)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var buf bytes.Buffer
+	if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	for i := 0; i < len(data); i++ {
+		if _, err := golox.ReadProgram(bytes.NewReader(data[:i]), golox.SourceHash(src), golox.NewInterpreter()); err == nil {
+			t.Errorf("Expected an error reading the first %d bytes", i)
+		}
+	}
+}
+
+// TestProgramResolution checks that the slots of shadowed local variables and
+// the tail calls the resolver found are restored with the program.
+func TestProgramResolution(t *testing.T) {
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	src := []byte(`var a = "global";
+{
+  var b = "b";
+  var a = "outer";
+  {
+    var c = "c";
+    var a = "inner";
+    fun f() { return a + b + c; }
+    print f();
+  }
+  print a;
+}
+print a;
+` + tailCallSource)
+
+	stmts, interp, lerr := parseAndResolve(src)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var buf bytes.Buffer
+	if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	interp = golox.NewInterpreter()
+	interp.SetOutput(&out)
+
+	loaded, err := golox.ReadProgram(&buf, golox.SourceHash(src), interp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, lerr = interp.Interpret(loaded); lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	if expected := "innerbc\nouter\nglobal\n100000\nfalse\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+// TestProgramInvalid checks that programs with a corrupted header, trailing
+// data or resolution data referring to the wrong nodes are rejected, and that
+// no corruption of a single byte panics.
+func TestProgramInvalid(t *testing.T) {
+	src := []byte("fun f(n) { if (n == 0) return 0; return f(n - 1); }\nprint f(3);\n")
+
+	stmts, interp, lerr := parseAndResolve(src)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var buf bytes.Buffer
+	if err := golox.WriteProgram(&buf, golox.SourceHash(src), stmts, interp); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	corrupt := func(i int, b byte) []byte {
+		res := append([]byte{}, data...)
+		res[i] = b
+
+		return res
+	}
+
+	for name, program := range map[string][]byte{
+		"magic":     corrupt(0, 'X'),
+		"version":   corrupt(8, 2),
+		"trailing":  append(append([]byte{}, data...), 0),
+		"tail call": corrupt(len(data)-1, 0),
+	} {
+		if _, err := golox.ReadProgram(bytes.NewReader(program), golox.SourceHash(src), golox.NewInterpreter()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	for i := range data {
+		for _, b := range []byte{0, 0x7f, 0xff, data[i] ^ 1} {
+			_, _ = golox.ReadProgram(bytes.NewReader(corrupt(i, b)), golox.SourceHash(src), golox.NewInterpreter())
+		}
+	}
+}
+
+func printStmts(stmts []golox.Stmt) string {
+	var b strings.Builder
+
+	for _, s := range stmts {
+		b.WriteString((&golox.AstPrinter{}).PrintStmt(s))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -1,7 +1,7 @@
 package golox
 
 type Stmt interface {
-	Accept(v StmtVisitor) (interface{}, *LoxError)
+	Accept(v StmtVisitor) (Value, *LoxError)
 }
 
 // ================ Block ================
@@ -10,7 +10,7 @@ type Block struct {
 	Stmts []Stmt
 }
 
-func (b *Block) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (b *Block) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptBlockStmt(b)
 }
 
@@ -20,17 +20,18 @@ type Expression struct {
 	Expr Expr
 }
 
-func (e *Expression) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (e *Expression) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptExpressionStmt(e)
 }
 
 // ================ Print ================
 
 type Print struct {
-	Expr Expr
+	Keyword Token
+	Expr    Expr
 }
 
-func (p *Print) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (p *Print) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptPrintStmt(p)
 }
 
@@ -38,45 +39,50 @@ func (p *Print) Accept(v StmtVisitor) (interface{}, *LoxError) {
 
 type Var struct {
 	Name        Token
+	Type        *TypeExpr
 	Initializer Expr
 }
 
-func (va *Var) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (va *Var) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptVarStmt(va)
 }
 
 // ================ Func ================
 
 type Func struct {
-	Name   Token
-	Params []Token
-	Body   []Stmt
+	Name       Token
+	Params     []Token
+	ParamTypes []*TypeExpr
+	ReturnType *TypeExpr
+	Body       []Stmt
 }
 
-func (f *Func) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (f *Func) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptFuncStmt(f)
 }
 
 // ================ If ================
 
 type If struct {
+	Keyword   Token
 	Condition Expr
 	Body      Stmt
 	ElseBody  Stmt
 }
 
-func (i *If) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (i *If) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptIfStmt(i)
 }
 
 // ================ While ================
 
 type While struct {
+	Keyword   Token
 	Condition Expr
 	Body      Stmt
 }
 
-func (w *While) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (w *While) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptWhileStmt(w)
 }
 
@@ -87,19 +93,46 @@ type Return struct {
 	Value   Expr
 }
 
-func (r *Return) Accept(v StmtVisitor) (interface{}, *LoxError) {
+func (r *Return) Accept(v StmtVisitor) (Value, *LoxError) {
 	return v.AcceptReturnStmt(r)
 }
 
+// ================ Throw ================
+
+type Throw struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (t *Throw) Accept(v StmtVisitor) (Value, *LoxError) {
+	return v.AcceptThrowStmt(t)
+}
+
+// ================ Try ================
+
+type Try struct {
+	Keyword     Token
+	Body        []Stmt
+	CatchName   Token
+	CatchBody   []Stmt
+	FinallyBody []Stmt
+}
+
+func (t *Try) Accept(v StmtVisitor) (Value, *LoxError) {
+	return v.AcceptTryStmt(t)
+}
+
 // ================ StmtVisitor ================
 
 type StmtVisitor interface {
-	AcceptBlockStmt(*Block) (interface{}, *LoxError)
-	AcceptExpressionStmt(*Expression) (interface{}, *LoxError)
-	AcceptPrintStmt(*Print) (interface{}, *LoxError)
-	AcceptVarStmt(*Var) (interface{}, *LoxError)
-	AcceptFuncStmt(*Func) (interface{}, *LoxError)
-	AcceptIfStmt(*If) (interface{}, *LoxError)
-	AcceptWhileStmt(*While) (interface{}, *LoxError)
-	AcceptReturnStmt(*Return) (interface{}, *LoxError)
+	AcceptBlockStmt(*Block) (Value, *LoxError)
+	AcceptExpressionStmt(*Expression) (Value, *LoxError)
+	AcceptPrintStmt(*Print) (Value, *LoxError)
+	AcceptVarStmt(*Var) (Value, *LoxError)
+	AcceptFuncStmt(*Func) (Value, *LoxError)
+	AcceptIfStmt(*If) (Value, *LoxError)
+	AcceptWhileStmt(*While) (Value, *LoxError)
+	AcceptReturnStmt(*Return) (Value, *LoxError)
+	AcceptThrowStmt(*Throw) (Value, *LoxError)
+	AcceptTryStmt(*Try) (Value, *LoxError)
 }
@@ -0,0 +1,48 @@
+package golox_test
+
+import (
+	"bytes"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+const tailCallSource = `
+fun loop(n, acc) {
+  if (n == 0) return acc;
+  return loop(n - 1, acc + 1);
+}
+print loop(100000, 0);
+
+fun even(n) { if (n == 0) return true; return odd(n - 1); }
+fun odd(n) { if (n == 0) return false; return even(n - 1); }
+print even(100001);
+`
+
+// Without tail calls these scripts need hundreds of megabytes of Go stack.
+func TestTailCallsRunInConstantStack(t *testing.T) {
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	stmts, err := golox.NewStreamParser(golox.NewScanner(strings.NewReader(tailCallSource))).Parse()
+	if err != nil {
+		t.Fatalf("Got error on p.Parse(): %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	interp := golox.NewInterpreter()
+	interp.SetOutput(out)
+
+	if err = golox.NewResolver(interp).Resolve(stmts); err != nil {
+		t.Fatalf("Got error on r.Resolve(): %v", err)
+	}
+
+	if _, err = interp.Interpret(stmts); err != nil {
+		t.Fatalf("Got error on interp.Interpret(): %v", err)
+	}
+
+	if out.String() != "100000\nfalse\n" {
+		t.Fatalf("Expected 100000 and false, got %q", out.String())
+	}
+}
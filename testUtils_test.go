@@ -1,55 +1,15 @@
 package golox_test
 
 import (
-	"fmt"
-	"log"
+	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
+	"testing"
 
 	. "github.com/agayev169/golox"
 )
 
-type AstPrinter struct {
-}
-
-func (ap *AstPrinter) AcceptBinaryExpr(b *Binary) (interface{}, error) {
-	return ap.parenthesize(b.Operator.Lexeme, b.Left, b.Right), nil
-}
-
-func (ap *AstPrinter) AcceptGroupingExpr(g *Grouping) (interface{}, error) {
-	return ap.parenthesize("group", g.Expr), nil
-}
-
-func (ap *AstPrinter) AcceptLiteralExpr(l *Literal) (interface{}, error) {
-	return fmt.Sprintf("%v", l.Value), nil
-}
-
-func (ap *AstPrinter) AcceptUnaryExpr(u *Unary) (interface{}, error) {
-	return ap.parenthesize(u.Operator.Lexeme, u.Right), nil
-}
-
-func (ap *AstPrinter) parenthesize(name string, exprs ...Expr) string {
-	sb := strings.Builder{}
-
-	sb.WriteString(fmt.Sprintf("(%s", name))
-
-	for _, expr := range exprs {
-		sb.WriteString(" ")
-
-		res, _ := expr.Accept(ap)
-
-		switch v := res.(type) {
-		case string:
-			sb.WriteString(v)
-		default:
-			log.Panicf("Expected the output of expr.Accept(ap) to be string, received %v\n", v)
-		}
-	}
-
-	sb.WriteString(")")
-
-	return sb.String()
-}
-
 // Comparators
 
 func areEqualExprs(e1, e2 Expr) bool {
@@ -66,7 +26,7 @@ func areEqualExprs(e1, e2 Expr) bool {
     l, _ := e1.Accept(ap)
     r, _ := e2.Accept(ap)
 
-	return l.(string) == r.(string)
+	return l.AsString() == r.AsString()
 }
 
 func areEqualLoxErrors(e1, e2 *LoxError) bool {
@@ -79,4 +39,67 @@ func areEqualLoxErrors(e1, e2 *LoxError) bool {
     }
 
     return e1.Number == e2.Number
-}
\ No newline at end of file
+}
+
+// Helpers
+
+func singleExpr(stmts []Stmt) Expr {
+	if len(stmts) != 1 {
+		return nil
+	}
+
+	e, ok := stmts[0].(*Expression)
+	if !ok {
+		return nil
+	}
+
+	return e.Expr
+}
+
+func parseAndResolve(src []byte) ([]Stmt, *Interpreter, *LoxError) {
+	stmts, err := NewStreamParser(NewScanner(bytes.NewReader(src))).Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	interp := NewInterpreter()
+	if err = NewResolver(interp).Resolve(stmts); err != nil {
+		return nil, nil, err
+	}
+
+	return stmts, interp, nil
+}
+
+// conformanceFiles returns the paths of the conformance tests in testdata.
+func conformanceFiles(t *testing.T) []string {
+	paths := make([]string, 0)
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".lox") {
+			paths = append(paths, path)
+		}
+
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return paths
+}
+
+// forEachConformanceFile runs f in a subtest named after the path of every
+// conformance test relative to testdata.
+func forEachConformanceFile(t *testing.T, f func(t *testing.T, path string, src []byte)) {
+	for _, path := range conformanceFiles(t) {
+		path := path
+
+		t.Run(strings.TrimSuffix(strings.TrimPrefix(path, "testdata"+string(filepath.Separator)), ".lox"), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f(t, path, src)
+		})
+	}
+}
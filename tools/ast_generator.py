@@ -28,7 +28,7 @@ def define_ast(
         f.writelines(
             [
                 f"type {base_name} interface {{\n",
-                f"   Accept(v {visitor_name}) (interface{{}}, *LoxError)\n",
+                f"   Accept(v {visitor_name}) (Value, *LoxError)\n",
                 "}\n",
                 "\n",
             ]
@@ -57,7 +57,7 @@ def define_ast(
                 varName = name[:2].lower()
 
             lines = lines + [
-                f"func ({varName} *{name}) Accept(v {visitor_name}) (interface{{}}, *LoxError) {{\n",
+                f"func ({varName} *{name}) Accept(v {visitor_name}) (Value, *LoxError) {{\n",
                 f"    return v.Accept{name}{base_name}({varName})\n",
                 "}\n",
             ]
@@ -66,7 +66,7 @@ def define_ast(
             f.writelines(lines)
 
             visitor_methods += [
-                f"    Accept{name}{base_name}(*{name}) (interface{{}}, *LoxError)\n"]
+                f"    Accept{name}{base_name}(*{name}) (Value, *LoxError)\n"]
 
         # Visitor
         f.writelines(
@@ -102,12 +102,17 @@ if __name__ == "__main__":
             ("Binary", [("left", "Expr"),
              ("operator", "Token"), ("right", "Expr")]),
             ("Grouping", [("expr", "Expr")]),
-            ("Literal", [("value", "interface{}")]),
+            ("Literal", [("value", "Value")]),
             ("Unary", [("operator", "Token"), ("right", "Expr")]),
             ("Call", [("callee", "Expr"), ("paren", "Token"), ("args", "[]Expr")]),
             ("Variable", [("name", "Token")]),
             ("Logical", [("left", "Expr"),
                          ("operator", "Token"), ("right", "Expr")]),
+            ("Lambda", [("decl", "*Func")]),
+            ("Get", [("object", "Expr"), ("name", "Token")]),
+            ("Conditional", [("condition", "Expr"), ("question", "Token"), ("then", "Expr"), ("else", "Expr")]),
+            ("CompoundAssign", [("name", "Token"), ("operator", "Token"), ("value", "Expr")]),
+            ("Increment", [("name", "Token"), ("operator", "Token"), ("postfix", "bool")]),
         ],
     )
 
@@ -118,13 +123,16 @@ if __name__ == "__main__":
         [
             ("Block", [("stmts", "[]Stmt")]),
             ("Expression", [("expr", "Expr")]),
-            ("Print", [("expr", "Expr")]),
-            ("Var", [("name", "Token"), ("initializer", "Expr")]),
-            ("Func", [("name", "Token"), ("params", "[]Token"),
-                      ("body", "[]Stmt")]),
-            ("If", [("condition", "Expr"), ("body", "Stmt"),
+            ("Print", [("keyword", "Token"), ("expr", "Expr")]),
+            ("Var", [("name", "Token"), ("type", "*TypeExpr"), ("initializer", "Expr")]),
+            ("Func", [("name", "Token"), ("params", "[]Token"), ("paramTypes", "[]*TypeExpr"),
+                      ("returnType", "*TypeExpr"), ("body", "[]Stmt")]),
+            ("If", [("keyword", "Token"), ("condition", "Expr"), ("body", "Stmt"),
                     ("elseBody", "Stmt")]),
-            ("While", [("condition", "Expr"), ("body", "Stmt")]),
-            ("Return", [("keyword", "Token"), ("value", "Expr")])
+            ("While", [("keyword", "Token"), ("condition", "Expr"), ("body", "Stmt")]),
+            ("Return", [("keyword", "Token"), ("value", "Expr")]),
+            ("Throw", [("keyword", "Token"), ("value", "Expr")]),
+            ("Try", [("keyword", "Token"), ("body", "[]Stmt"), ("catchName", "Token"),
+                     ("catchBody", "[]Stmt"), ("finallyBody", "[]Stmt")]),
         ],
     )
@@ -0,0 +1,35 @@
+package golox
+
+import "strings"
+
+// TypeExpr is a type annotation, such as the `num` in `var x: num = 1;`.
+// Annotations are only used by the Checker; the Interpreter ignores them.
+//
+//	num, int, str, bool, nil, any   Name is an IDENTIFIER or NIL
+//	fun                             any function
+//	fun(num, str): bool             a function with the given signature
+type TypeExpr struct {
+	Name Token
+	// The parameter types of a function type, nil if it has no signature.
+	Params []*TypeExpr
+	// The return type of a function type with a signature, if annotated.
+	Return *TypeExpr
+}
+
+func (t *TypeExpr) String() string {
+	if t.Name.Type != FUN || t.Params == nil {
+		return t.Name.Lexeme
+	}
+
+	params := make([]string, 0, len(t.Params))
+	for _, p := range t.Params {
+		params = append(params, p.String())
+	}
+
+	res := "fun(" + strings.Join(params, ", ") + ")"
+	if t.Return != nil {
+		res += ": " + t.Return.String()
+	}
+
+	return res
+}
@@ -1,19 +1,10 @@
 package golox
 
 import (
-	"fmt"
 	"log"
+	"unicode"
 )
 
-func fatal(err error) {
-	if err == nil {
-		return
-	}
-
-	// log.Printf("[ERR] %s\n", err)
-	panic(fmt.Sprintf("[ERR] %s\n", err))
-}
-
 func warn(err error) {
 	if err == nil {
 		return
@@ -22,19 +13,29 @@ func warn(err error) {
 	log.Printf("[WARN]: %s\n", err)
 }
 
-func isDigit(b byte) bool {
-	return b >= '0' && b <= '9'
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isDigitIn reports whether r is a digit in base 2, 10 or 16.
+func isDigitIn(r rune, base int) bool {
+	switch base {
+	case 2:
+		return r == '0' || r == '1'
+	case 16:
+		return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+	}
+
+	return isDigit(r)
 }
 
-func isAlpha(b byte) bool {
-	return (b >= 'a' && b <= 'z') ||
-		(b >= 'A' && b <= 'Z') ||
-		b == '_'
+// Identifiers start with a Unicode letter or `_`, followed by any number of
+// Unicode letters, decimal digits, combining marks or `_`. Number literals
+// only use the ASCII digits.
+func isAlpha(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
 }
 
-func isAlphaNumeric(b byte) bool {
-	return (b >= 'a' && b <= 'z') ||
-		(b >= 'A' && b <= 'Z') ||
-		(b >= '0' && b <= '9') ||
-		b == '_'
+func isAlphaNumeric(r rune) bool {
+	return isAlpha(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc)
 }
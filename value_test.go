@@ -0,0 +1,64 @@
+package golox_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/agayev169/golox"
+)
+
+func TestValueTruthiness(t *testing.T) {
+	tests := map[string]struct {
+		Value    golox.Value
+		Expected bool
+	}{
+		"nil":          {golox.NilValue, false},
+		"false":        {golox.BoolValue(false), false},
+		"true":         {golox.BoolValue(true), true},
+		"zero":         {golox.NumberValue(0), true},
+		"empty string": {golox.StringValue(""), true},
+	}
+
+	for k, tv := range tests {
+		if tv.Value.IsTruthy() != tv.Expected {
+			t.Fatalf("Failed on test %s. Expected: %v, got: %v", k, tv.Expected, tv.Value.IsTruthy())
+		}
+	}
+}
+
+func TestValueEquals(t *testing.T) {
+	obj := &struct{}{}
+
+	tests := map[string]struct {
+		Left, Right golox.Value
+		Expected    bool
+	}{
+		"nil":               {golox.NilValue, golox.NilValue, true},
+		"numbers":           {golox.NumberValue(1), golox.NumberValue(1), true},
+		"different numbers": {golox.NumberValue(1), golox.NumberValue(2), false},
+		"strings":           {golox.StringValue("a"), golox.StringValue("a"), true},
+		"bool and number":   {golox.BoolValue(true), golox.NumberValue(1), false},
+		"nil and false":     {golox.NilValue, golox.BoolValue(false), false},
+		"same object":       {golox.ObjectValue(obj), golox.ObjectValue(obj), true},
+		"different objects": {golox.ObjectValue(obj), golox.ObjectValue(&struct{ a int }{}), false},
+		"string and number": {golox.StringValue("1"), golox.NumberValue(1), false},
+		"int and number":    {golox.IntValue(1), golox.NumberValue(1), true},
+		"big ints":          {golox.BigIntValue(bigInt("1000000000000000000000000000000")), golox.BigIntValue(bigInt("1000000000000000000000000000000")), true},
+		"small big int":     {golox.BigIntValue(big.NewInt(7)), golox.IntValue(7), true},
+		"big int and int":   {golox.BigIntValue(bigInt("1000000000000000000000000000000")), golox.IntValue(1), false},
+		"int and nan":       {golox.IntValue(0), golox.NumberValue(math.NaN()), false},
+	}
+
+	for k, tv := range tests {
+		if tv.Left.Equals(tv.Right) != tv.Expected {
+			t.Fatalf("Failed on test %s. Expected: %v", k, tv.Expected)
+		}
+	}
+}
+
+func bigInt(s string) *big.Int {
+	b, _ := new(big.Int).SetString(s, 10)
+
+	return b
+}
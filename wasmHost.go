@@ -0,0 +1,99 @@
+package golox
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// WasmHost implements the functions a module compiled by the WatCompiler
+// imports, whichever WebAssembly runtime it is run with. The runtime passes
+// them the memory of the module, to which the values and the names they refer
+// to point.
+type WasmHost struct {
+	out io.Writer
+	// The runtime error the module reported, if any. The module traps right
+	// after reporting it.
+	Err *LoxError
+}
+
+// NewWasmHost returns a WasmHost printing to out.
+func NewWasmHost(out io.Writer) *WasmHost {
+	return &WasmHost{out: out}
+}
+
+// Print implements the `print` import, printing the value v.
+func (h *WasmHost) Print(mem []byte, v uint64) {
+	fmt.Fprintln(h.out, h.stringify(mem, v))
+}
+
+func (h *WasmHost) stringify(mem []byte, v uint64) string {
+	switch {
+	case v == watNil:
+		return "nil"
+	case v == watFalse:
+		return "false"
+	case v == watTrue:
+		return "true"
+	case v&watFunction == watFunction:
+		p := uint32(v)
+
+		return fmt.Sprintf("<fn %s>", wasmString(mem, binary.LittleEndian.Uint32(mem[p+12:]), binary.LittleEndian.Uint32(mem[p+16:])))
+	case v>>48 == watInt>>48:
+		return strconv.FormatInt(int64(v<<16)>>16, 10)
+	case v&watQNaN != watQNaN:
+		return Stringify(NumberValue(math.Float64frombits(v)))
+	}
+
+	return "<invalid>"
+}
+
+// Error implements the `error` import, reporting the runtime error with the
+// given code on line. The meaning of a and b depends on the code:
+//
+//	1  operands of an arithmetic or comparison operator are not numbers
+//	2  operands of `+` are not numbers
+//	3  the operand of the operator at the address a of length b is not a number
+//	4  the callee is not a function
+//	5  a function of arity a is called with b arguments
+//	6  the variable named at a of length b is undefined
+//	7  the variable named at a of length b is read before being assigned
+//	8  the global variable named at a of length b is defined twice
+//	9  an integer result does not fit in 48 bits
+func (h *WasmHost) Error(mem []byte, code, line, a, b uint32) *LoxError {
+	t := Token{Line: int(line)}
+
+	switch code {
+	case watErrOperands:
+		h.Err = genError(t, InvalidOperand, "Operands must be numbers.")
+	case watErrPlus:
+		h.Err = genError(t, InvalidOperand, "Operands must be two numbers or two strings.")
+	case watErrOperand:
+		h.Err = genError(t, InvalidOperand, fmt.Sprintf("Expected a number but found `%s`.", wasmString(mem, a, b)))
+	case watErrCall:
+		h.Err = genError(t, InvalidCall, "Can only call functions and classes.")
+	case watErrArity:
+		h.Err = genError(t, InvalidArity, fmt.Sprintf("Expected %d arguments but got %d.", a, b))
+	case watErrUndefined:
+		t.Lexeme = wasmString(mem, a, b)
+		h.Err = genUndefVarError(t)
+	case watErrUnassigned:
+		t.Lexeme = wasmString(mem, a, b)
+		h.Err = CheckAssigned(t, Uninitialized)
+	case watErrRedefined:
+		h.Err = genError(t, NameAlreadyDefined, fmt.Sprintf("Cannot redefine '%s'\n", wasmString(mem, a, b)))
+	case watErrOverflow:
+		h.Err = genError(t, Unsupported, "The WebAssembly backend does not support integers of more than 48 bits.")
+	default:
+		h.Err = genError(t, Unsupported, fmt.Sprintf("Unknown runtime error %d.", code))
+	}
+
+	return h.Err
+}
+
+// wasmString returns the n bytes of mem at addr as a string.
+func wasmString(mem []byte, addr, n uint32) string {
+	return string(mem[addr : addr+n])
+}
@@ -0,0 +1,681 @@
+package golox_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// assembleWat encodes a module in the text format to the binary format. It
+// understands the subset of the text format the WatCompiler emits: module
+// fields in their abbreviated forms and function bodies of plain
+// instructions.
+func assembleWat(src []byte) ([]byte, error) {
+	toks, err := watTokens(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &watParser{toks: toks}
+
+	module, err := p.sexp()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(module.list) == 0 || module.list[0].atom != "module" {
+		return nil, fmt.Errorf("expected a module")
+	}
+
+	a := &watAssembler{funcs: make(map[string]uint32), globals: make(map[string]uint32), types: make(map[string]uint32)}
+
+	return a.module(module.list[1:])
+}
+
+// watNode is an atom or a list of nodes.
+type watNode struct {
+	atom string
+	list []*watNode
+}
+
+func (n *watNode) isList(head string) bool {
+	return n.list != nil && len(n.list) > 0 && n.list[0].atom == head
+}
+
+// watTokens splits src into parentheses, atoms and strings, which keep their
+// quotes.
+func watTokens(src string) ([]string, error) {
+	toks := make([]string, 0)
+
+	for i := 0; i < len(src); {
+		switch ch := src[i]; {
+		case ch == ' ' || ch == '\n' || ch == '\t' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')':
+			toks = append(toks, src[i:i+1])
+			i++
+		case ch == '"':
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+			}
+
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+
+			toks = append(toks, src[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(src) && !strings.ContainsRune(" \n\t\r()\"", rune(src[j])) {
+				j++
+			}
+
+			toks = append(toks, src[i:j])
+			i = j
+		}
+	}
+
+	return toks, nil
+}
+
+type watParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *watParser) sexp() (*watNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of module")
+	}
+
+	tok := p.toks[p.pos]
+	p.pos++
+
+	switch tok {
+	case ")":
+		return nil, fmt.Errorf("unexpected )")
+	case "(":
+		n := &watNode{list: make([]*watNode, 0)}
+
+		for p.pos < len(p.toks) && p.toks[p.pos] != ")" {
+			child, err := p.sexp()
+			if err != nil {
+				return nil, err
+			}
+
+			n.list = append(n.list, child)
+		}
+
+		if p.pos >= len(p.toks) {
+			return nil, fmt.Errorf("missing )")
+		}
+
+		p.pos++
+
+		return n, nil
+	}
+
+	return &watNode{atom: tok}, nil
+}
+
+var watValTypes = map[string]byte{"i32": 0x7f, "i64": 0x7e, "f32": 0x7d, "f64": 0x7c}
+
+// The opcodes of the instructions without immediates.
+var watSimpleOps = map[string]byte{
+	"unreachable": 0x00, "else": 0x05, "end": 0x0b, "return": 0x0f, "drop": 0x1a, "select": 0x1b,
+	"i32.eqz": 0x45, "i32.eq": 0x46, "i32.ne": 0x47, "i32.lt_s": 0x48, "i32.lt_u": 0x49, "i32.gt_s": 0x4a,
+	"i32.gt_u": 0x4b, "i32.le_s": 0x4c, "i32.le_u": 0x4d, "i32.ge_s": 0x4e, "i32.ge_u": 0x4f,
+	"i64.eqz": 0x50, "i64.eq": 0x51, "i64.ne": 0x52,
+	"f64.eq": 0x61, "f64.ne": 0x62, "f64.lt": 0x63, "f64.gt": 0x64, "f64.le": 0x65, "f64.ge": 0x66,
+	"i32.add": 0x6a, "i32.sub": 0x6b, "i32.mul": 0x6c, "i32.and": 0x71, "i32.or": 0x72, "i32.xor": 0x73,
+	"i32.shl": 0x74, "i32.shr_s": 0x75, "i32.shr_u": 0x76,
+	"i64.add": 0x7c, "i64.sub": 0x7d, "i64.mul": 0x7e, "i64.and": 0x83, "i64.or": 0x84, "i64.xor": 0x85, "i64.shl": 0x86,
+	"i64.shr_s": 0x87, "i64.shr_u": 0x88,
+	"f64.abs": 0x99, "f64.neg": 0x9a, "f64.add": 0xa0, "f64.sub": 0xa1, "f64.mul": 0xa2, "f64.div": 0xa3,
+	"i32.wrap_i64": 0xa7, "f64.convert_i64_s": 0xb9, "i64.extend_i32_s": 0xac, "i64.extend_i32_u": 0xad,
+	"i64.reinterpret_f64": 0xbd, "f64.reinterpret_i64": 0xbf,
+}
+
+// The opcodes and natural alignments of the memory instructions.
+var watMemoryOps = map[string][2]byte{
+	"i32.load": {0x28, 2}, "i64.load": {0x29, 3}, "f64.load": {0x2b, 3},
+	"i32.store": {0x36, 2}, "i64.store": {0x37, 3}, "f64.store": {0x39, 3},
+}
+
+type watAssembler struct {
+	// The indices of functions, globals and types by name.
+	funcs, globals map[string]uint32
+	// The indices of types by name and by signature.
+	types map[string]uint32
+
+	typeSec, importSec, funcSec, tableSec, memSec, globalSec, exportSec, elemSec, codeSec, dataSec watVec
+}
+
+// watVec is the encoding of a vector, whose length is counted as it grows.
+type watVec struct {
+	n int
+	b bytes.Buffer
+}
+
+func (v *watVec) add(b []byte) {
+	v.n++
+	v.b.Write(b)
+}
+
+func (v *watVec) bytes() []byte {
+	return append(watU32(uint32(v.n)), v.b.Bytes()...)
+}
+
+func (a *watAssembler) module(fields []*watNode) ([]byte, error) {
+	// Functions and types may be referred to before they are defined.
+	nImports, nFuncs := 0, 0
+	for _, f := range fields {
+		if f.isList("import") {
+			nImports++
+		}
+	}
+
+	for _, f := range fields {
+		switch {
+		case f.isList("import"):
+			a.funcs[f.list[3].list[1].atom] = uint32(len(a.funcs))
+		case f.isList("func"):
+			a.funcs[f.list[1].atom] = uint32(nImports + nFuncs)
+			nFuncs++
+		case f.isList("type"):
+			if _, err := a.funcType(f.list[1].atom, f.list[2].list[1:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range fields {
+		var err error
+
+		switch f.list[0].atom {
+		case "type":
+		case "import":
+			err = a.importFunc(f)
+		case "memory":
+			a.memSec.add(append([]byte{0}, watU32(uint32(a.int(f.list[len(f.list)-1].atom)))...))
+			a.exportSec.add(append(watName(a.str(f.list[1].list[1].atom)), append([]byte{2}, watU32(0)...)...))
+		case "table":
+			a.tableSec.add(append([]byte{0x70, 0}, watU32(uint32(a.int(f.list[1].atom)))...))
+		case "elem":
+			err = a.elem(f)
+		case "data":
+			err = a.data(f)
+		case "global":
+			err = a.global(f)
+		case "func":
+			err = a.function(f)
+		default:
+			err = fmt.Errorf("unsupported module field %s", f.list[0].atom)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+
+	b.Write([]byte{0, 'a', 's', 'm', 1, 0, 0, 0})
+
+	for i, sec := range []*watVec{&a.typeSec, &a.importSec, &a.funcSec, &a.tableSec, &a.memSec, &a.globalSec, &a.exportSec, nil, &a.elemSec, &a.codeSec, &a.dataSec} {
+		if sec == nil || sec.n == 0 {
+			continue
+		}
+
+		content := sec.bytes()
+		b.WriteByte(byte(i + 1))
+		b.Write(watU32(uint32(len(content))))
+		b.Write(content)
+	}
+
+	return b.Bytes(), nil
+}
+
+// funcType returns the index of the type of functions with the params and
+// results in sig, adding it if needed under name, which may be empty.
+func (a *watAssembler) funcType(name string, sig []*watNode) (uint32, error) {
+	var params, results []byte
+
+	for _, n := range sig {
+		if !n.isList("param") && !n.isList("result") {
+			continue
+		}
+
+		for _, t := range n.list[1:] {
+			if strings.HasPrefix(t.atom, "$") {
+				continue
+			}
+
+			vt, ok := watValTypes[t.atom]
+			if !ok {
+				return 0, fmt.Errorf("unknown type %s", t.atom)
+			}
+
+			if n.isList("param") {
+				params = append(params, vt)
+			} else {
+				results = append(results, vt)
+			}
+		}
+	}
+
+	enc := append([]byte{0x60}, watU32(uint32(len(params)))...)
+	enc = append(enc, params...)
+	enc = append(enc, watU32(uint32(len(results)))...)
+	enc = append(enc, results...)
+
+	idx, ok := a.types[string(enc)]
+	if !ok {
+		idx = uint32(a.typeSec.n)
+		a.types[string(enc)] = idx
+		a.typeSec.add(enc)
+	}
+
+	if name != "" {
+		a.types[name] = idx
+	}
+
+	return idx, nil
+}
+
+func (a *watAssembler) importFunc(f *watNode) error {
+	idx, err := a.funcType("", f.list[3].list[2:])
+	if err != nil {
+		return err
+	}
+
+	enc := append(watName(a.str(f.list[1].atom)), watName(a.str(f.list[2].atom))...)
+	enc = append(enc, 0)
+	a.importSec.add(append(enc, watU32(idx)...))
+
+	return nil
+}
+
+func (a *watAssembler) elem(f *watNode) error {
+	enc := []byte{0}
+
+	offset, err := a.constExpr(f.list[1])
+	if err != nil {
+		return err
+	}
+
+	enc = append(enc, offset...)
+	enc = append(enc, watU32(uint32(len(f.list)-2))...)
+	for _, fn := range f.list[2:] {
+		enc = append(enc, watU32(a.funcs[fn.atom])...)
+	}
+
+	a.elemSec.add(enc)
+
+	return nil
+}
+
+func (a *watAssembler) data(f *watNode) error {
+	offset, err := a.constExpr(f.list[1])
+	if err != nil {
+		return err
+	}
+
+	enc := append([]byte{0}, offset...)
+	a.dataSec.add(append(enc, watName(a.str(f.list[2].atom))...))
+
+	return nil
+}
+
+func (a *watAssembler) global(f *watNode) error {
+	a.globals[f.list[1].atom] = uint32(a.globalSec.n)
+
+	typ, mut := f.list[2], byte(0)
+	if typ.isList("mut") {
+		typ, mut = typ.list[1], 1
+	}
+
+	init, err := a.constExpr(f.list[3])
+	if err != nil {
+		return err
+	}
+
+	a.globalSec.add(append([]byte{watValTypes[typ.atom], mut}, init...))
+
+	return nil
+}
+
+// constExpr encodes a constant expression such as (i32.const 0).
+func (a *watAssembler) constExpr(n *watNode) ([]byte, error) {
+	var code bytes.Buffer
+
+	if err := a.instrs(&code, n.list, nil); err != nil {
+		return nil, err
+	}
+
+	return append(code.Bytes(), 0x0b), nil
+}
+
+func (a *watAssembler) function(f *watNode) error {
+	locals := make(map[string]uint32)
+	nParams := 0
+
+	var localTypes []byte
+
+	rest := f.list[2:]
+	var typeIdx *uint32
+
+	for len(rest) > 0 && rest[0].list != nil {
+		n := rest[0]
+
+		switch n.list[0].atom {
+		case "export":
+			a.exportSec.add(append(watName(a.str(n.list[1].atom)), append([]byte{0}, watU32(a.funcs[f.list[1].atom])...)...))
+		case "type":
+			idx := a.types[n.list[1].atom]
+			typeIdx = &idx
+		case "param", "local":
+			if len(n.list) == 3 && strings.HasPrefix(n.list[1].atom, "$") {
+				locals[n.list[1].atom] = uint32(len(locals))
+				if n.list[0].atom == "param" {
+					nParams++
+				} else {
+					localTypes = append(localTypes, watValTypes[n.list[2].atom])
+				}
+			} else {
+				return fmt.Errorf("unsupported declaration in %s", f.list[1].atom)
+			}
+		case "result":
+		default:
+			return fmt.Errorf("unexpected %s in %s", n.list[0].atom, f.list[1].atom)
+		}
+
+		rest = rest[1:]
+	}
+
+	if typeIdx == nil {
+		idx, err := a.funcType("", f.list[2:len(f.list)-len(rest)])
+		if err != nil {
+			return err
+		}
+
+		typeIdx = &idx
+	}
+
+	a.funcSec.add(watU32(*typeIdx))
+
+	var code bytes.Buffer
+
+	code.Write(watU32(uint32(len(localTypes))))
+	for _, t := range localTypes {
+		code.Write([]byte{1, t})
+	}
+
+	if err := a.instrs(&code, rest, locals); err != nil {
+		return fmt.Errorf("%s: %w", f.list[1].atom, err)
+	}
+
+	code.WriteByte(0x0b)
+	a.codeSec.add(append(watU32(uint32(code.Len())), code.Bytes()...))
+
+	return nil
+}
+
+// instrs encodes the instructions in ns to code.
+func (a *watAssembler) instrs(code *bytes.Buffer, ns []*watNode, locals map[string]uint32) error {
+	for i := 0; i < len(ns); i++ {
+		op := ns[i].atom
+
+		// next returns the immediate following the instruction.
+		next := func() (*watNode, error) {
+			if i+1 >= len(ns) {
+				return nil, fmt.Errorf("missing immediate of %s", op)
+			}
+
+			i++
+
+			return ns[i], nil
+		}
+
+		if b, ok := watSimpleOps[op]; ok {
+			code.WriteByte(b)
+
+			continue
+		}
+
+		if m, ok := watMemoryOps[op]; ok {
+			align, offset := uint32(m[1]), uint32(0)
+			for i+1 < len(ns) && (strings.HasPrefix(ns[i+1].atom, "offset=") || strings.HasPrefix(ns[i+1].atom, "align=")) {
+				i++
+
+				kv := strings.SplitN(ns[i].atom, "=", 2)
+				v := uint32(a.int(kv[1]))
+				if kv[0] == "offset" {
+					offset = v
+				} else {
+					align = uint32(math.Log2(float64(v)))
+				}
+			}
+
+			code.WriteByte(m[0])
+			code.Write(watU32(align))
+			code.Write(watU32(offset))
+
+			continue
+		}
+
+		switch op {
+		case "block", "loop", "if":
+			code.WriteByte(map[string]byte{"block": 0x02, "loop": 0x03, "if": 0x04}[op])
+
+			if i+1 < len(ns) && ns[i+1].isList("result") {
+				i++
+				code.WriteByte(watValTypes[ns[i].list[1].atom])
+			} else {
+				code.WriteByte(0x40)
+			}
+		case "br", "br_if":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			code.WriteByte(map[string]byte{"br": 0x0c, "br_if": 0x0d}[op])
+			code.Write(watU32(uint32(a.int(n.atom))))
+		case "local.get", "local.set", "local.tee":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			idx, ok := locals[n.atom]
+			if !ok {
+				return fmt.Errorf("unknown local %s", n.atom)
+			}
+
+			code.WriteByte(map[string]byte{"local.get": 0x20, "local.set": 0x21, "local.tee": 0x22}[op])
+			code.Write(watU32(idx))
+		case "global.get", "global.set":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			idx, ok := a.globals[n.atom]
+			if !ok {
+				return fmt.Errorf("unknown global %s", n.atom)
+			}
+
+			code.WriteByte(map[string]byte{"global.get": 0x23, "global.set": 0x24}[op])
+			code.Write(watU32(idx))
+		case "call":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			idx, ok := a.funcs[n.atom]
+			if !ok {
+				return fmt.Errorf("unknown function %s", n.atom)
+			}
+
+			code.WriteByte(0x10)
+			code.Write(watU32(idx))
+		case "call_indirect":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			idx, ok := a.types[n.list[1].atom]
+			if !n.isList("type") || !ok {
+				return fmt.Errorf("unknown type of call_indirect")
+			}
+
+			code.WriteByte(0x11)
+			code.Write(watU32(idx))
+			code.WriteByte(0)
+		case "memory.size", "memory.grow":
+			code.WriteByte(map[string]byte{"memory.size": 0x3f, "memory.grow": 0x40}[op])
+			code.WriteByte(0)
+		case "i32.const", "i64.const":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			v := a.int(n.atom)
+			if op == "i32.const" {
+				code.WriteByte(0x41)
+				v = int64(int32(v))
+			} else {
+				code.WriteByte(0x42)
+			}
+
+			code.Write(watS64(v))
+		case "f64.const":
+			n, err := next()
+			if err != nil {
+				return err
+			}
+
+			f, err := watFloat(n.atom)
+			if err != nil {
+				return err
+			}
+
+			code.WriteByte(0x44)
+			bits := math.Float64bits(f)
+			for j := 0; j < 8; j++ {
+				code.WriteByte(byte(bits >> (8 * j)))
+			}
+		default:
+			return fmt.Errorf("unsupported instruction %q", op)
+		}
+	}
+
+	return nil
+}
+
+// int parses an integer literal, which may be hexadecimal and, if it is too
+// large for an int64, is taken modulo 2^64.
+func (a *watAssembler) int(s string) int64 {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	u, err := strconv.ParseUint(strings.ReplaceAll(s, "_", ""), 0, 64)
+	if err != nil {
+		panic(fmt.Sprintf("invalid integer %s", s))
+	}
+
+	if neg {
+		return -int64(u)
+	}
+
+	return int64(u)
+}
+
+func watFloat(s string) (float64, error) {
+	switch s {
+	case "nan":
+		return math.NaN(), nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// str decodes a string literal.
+func (a *watAssembler) str(lit string) string {
+	lit = lit[1 : len(lit)-1]
+
+	var b strings.Builder
+
+	for i := 0; i < len(lit); i++ {
+		if lit[i] != '\\' {
+			b.WriteByte(lit[i])
+
+			continue
+		}
+
+		i++
+
+		switch lit[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\'', '\\':
+			b.WriteByte(lit[i])
+		default:
+			v, _ := strconv.ParseUint(lit[i:i+2], 16, 8)
+			b.WriteByte(byte(v))
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+func watName(s string) []byte {
+	return append(watU32(uint32(len(s))), s...)
+}
+
+func watU32(v uint32) []byte {
+	var b []byte
+
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+
+		if v == 0 {
+			return append(b, c)
+		}
+
+		b = append(b, c|0x80)
+	}
+}
+
+func watS64(v int64) []byte {
+	var b []byte
+
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+
+		b = append(b, c|0x80)
+	}
+}
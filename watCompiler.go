@@ -0,0 +1,1167 @@
+package golox
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// WatCompiler compiles a resolved program to a WebAssembly module in the text
+// format. It supports the subset of Lox without strings, objects and
+// exceptions: numbers, booleans, nil, functions and closures.
+//
+// Every value is an i64: floating-point numbers are their float64 bits, and
+// the other values are quiet NaNs that arithmetic never produces (see watNil,
+// watInt and watFunction). Integers keep their low 48 bits in the payload, so
+// that they stay exact; a literal or a result that does not fit is reported
+// as unsupported rather than silently rounded.
+// Variables are kept in environments allocated in the linear memory, one for
+// every scope declaring variables, holding the environment it is nested in and
+// a slot for each variable:
+//
+//	offset 0  enclosing environment (i32)
+//	offset 8  slot 0 (i64), ...
+//
+// so the depth and slot the Resolver recorded in the Interpreter locate a
+// local variable, and closures are functions paired with the environment they
+// were created in. Functions are called through a table, with their closure
+// and their arguments. Global variables are WebAssembly globals.
+//
+// The module imports the functions printing values and reporting runtime
+// errors from the host, which WasmHost implements, exports its memory and runs
+// the script with its exported main function. The tests assemble the text with
+// a small assembler of their own, and only check it with the reference
+// wat2wasm when it is installed.
+type WatCompiler struct {
+	interp *Interpreter
+	// The native functions, which the module does not have.
+	natives *Env
+	scopes  []*watScope
+	// The function being compiled.
+	fn *watFunc
+	// The compiled Lox functions, in the order of the table.
+	funcs []string
+	// The arities functions are defined and called with.
+	arities map[int]bool
+	globals map[string]string
+	// The declarations of the globals, in the order they were first used.
+	globalDecls []string
+	data        strings.Builder
+	strs        map[string]int
+	// The position of the statement being compiled.
+	pos Token
+	err *LoxError
+}
+
+// watScope is a local scope. Only a scope declaring variables gets an
+// environment.
+type watScope struct {
+	vars []watVar
+	heap bool
+}
+
+type watVar struct {
+	// Set if the variable is declared without an initializer, so that it must
+	// be checked to be assigned whenever it is read.
+	unassigned bool
+}
+
+type watFunc struct {
+	code  []string
+	depth int
+	// The number of calls made, each of which keeps its callee in a local.
+	calls int
+}
+
+// The values that are not floating-point numbers. Integers hold their value
+// in the low 48 bits and functions the address of their closure in the low 32
+// bits.
+const (
+	watNil      uint64 = 0x7ffc000000000001
+	watFalse    uint64 = 0x7ffc000000000002
+	watTrue     uint64 = 0x7ffc000000000003
+	watUnset    uint64 = 0x7ffc000000000004
+	watUndef    uint64 = 0x7ffc000000000005
+	watInt      uint64 = 0x7ffd000000000000
+	watFunction uint64 = 0xfffc000000000000
+	watQNaN     uint64 = 0x7ffc000000000000
+)
+
+// The range of the integers a module supports.
+const (
+	watIntMin = -1 << 47
+	watIntMax = 1<<47 - 1
+)
+
+// The codes of runtime errors the module reports, see WasmHost.Error.
+const (
+	watErrOperands = iota + 1
+	watErrPlus
+	watErrOperand
+	watErrCall
+	watErrArity
+	watErrUndefined
+	watErrUnassigned
+	watErrRedefined
+	watErrOverflow
+)
+
+// The operators with the name of their runtime function.
+var watOperators = map[TokenType]string{
+	PLUS:          "$add",
+	MINUS:         "$sub",
+	STAR:          "$mul",
+	SLASH:         "$div",
+	GREATER:       "$gt",
+	GREATER_EQUAL: "$ge",
+	LESS:          "$lt",
+	LESS_EQUAL:    "$le",
+}
+
+// The lexemes in the error messages of unary operators start the data.
+const (
+	watDataStart = 16
+	watMinus     = watDataStart
+	watPlusPlus  = watMinus + 1
+	watMinusMin  = watPlusPlus + 2
+)
+
+// NewWatCompiler returns a WatCompiler compiling statements resolved with
+// interp.
+func NewWatCompiler(interp *Interpreter) *WatCompiler {
+	c := &WatCompiler{
+		interp: interp, natives: NewGlobalEnv(),
+		arities: make(map[int]bool), globals: make(map[string]string), strs: make(map[string]int),
+	}
+	c.str("-++--")
+
+	return c
+}
+
+// Compile returns a module running stmts, or an error at the first construct
+// outside the supported subset.
+func (c *WatCompiler) Compile(stmts []Stmt) ([]byte, *LoxError) {
+	c.fn = &watFunc{}
+	c.stmts(stmts)
+	main := c.funcText("(func $main (export \"main\")", c.fn)
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("(module\n")
+	b.WriteString("  (import \"lox\" \"print\" (func $print (param i64)))\n")
+	b.WriteString("  (import \"lox\" \"error\" (func $error (param i32 i32 i32 i32)))\n")
+	b.WriteString("  (memory (export \"memory\") 1)\n")
+
+	arities := make([]int, 0, len(c.arities))
+	for n := range c.arities {
+		arities = append(arities, n)
+	}
+	sort.Ints(arities)
+
+	for _, n := range arities {
+		fmt.Fprintf(&b, "  (type $fn%d (func (param i32)%s (result i64)))\n", n, strings.Repeat(" (param i64)", n))
+	}
+
+	fmt.Fprintf(&b, "  (table %d funcref)\n", len(c.funcs))
+	if len(c.funcs) > 0 {
+		b.WriteString("  (elem (i32.const 0)")
+		for i := range c.funcs {
+			fmt.Fprintf(&b, " $f%d", i)
+		}
+		b.WriteString(")\n")
+	}
+
+	fmt.Fprintf(&b, "  (data (i32.const %d) \"%s\")\n", watDataStart, watString(c.data.String()))
+	fmt.Fprintf(&b, "  (global $heap (mut i32) (i32.const %d))\n", (watDataStart+c.data.Len()+7)&^7)
+	fmt.Fprintf(&b, "  (global $nil i64 (i64.const %#x))\n", watNil)
+	fmt.Fprintf(&b, "  (global $false i64 (i64.const %#x))\n", watFalse)
+	fmt.Fprintf(&b, "  (global $true i64 (i64.const %#x))\n", watTrue)
+	fmt.Fprintf(&b, "  (global $unset i64 (i64.const %#x))\n", watUnset)
+	fmt.Fprintf(&b, "  (global $undef i64 (i64.const %#x))\n", watUndef)
+	for _, g := range c.globalDecls {
+		fmt.Fprintf(&b, "  (global %s (mut i64) (i64.const %#x))\n", g, watUndef)
+	}
+
+	b.WriteString(watRuntime)
+	b.WriteString(watArithmetic())
+	for _, f := range c.funcs {
+		b.WriteString(f)
+	}
+	b.WriteString(main)
+	b.WriteString(")\n")
+
+	return []byte(b.String()), nil
+}
+
+// funcText returns the text of the function compiled to fn, starting with
+// header.
+func (c *WatCompiler) funcText(header string, fn *watFunc) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "  %s\n    (local $env i32) (local $v i64) (local $l i64) (local $o i64)", header)
+	for i := 0; i < fn.calls; i++ {
+		fmt.Fprintf(&b, " (local $c%d i32)", i)
+	}
+	b.WriteString("\n")
+
+	for _, l := range fn.code {
+		fmt.Fprintf(&b, "    %s\n", l)
+	}
+
+	s := b.String()
+
+	return s[:len(s)-1] + ")\n"
+}
+
+// Statements
+
+func (c *WatCompiler) stmts(ss []Stmt) {
+	for _, s := range ss {
+		c.stmt(s)
+	}
+}
+
+func (c *WatCompiler) stmt(s Stmt) {
+	switch st := s.(type) {
+	case *Block:
+		c.block(st.Stmts)
+	case *Expression:
+		c.expr(st.Expr)
+		c.emit("drop")
+	case *Print:
+		c.pos = st.Keyword
+		c.expr(st.Expr)
+		c.emit("call $print")
+	case *Var:
+		c.pos = st.Name
+		c.define(st.Name, st.Initializer == nil, func() {
+			if st.Initializer == nil {
+				c.emit("global.get $unset")
+			} else {
+				c.expr(st.Initializer)
+			}
+		})
+	case *Func:
+		c.pos = st.Name
+		c.define(st.Name, false, func() { c.function(st) })
+	case *If:
+		c.pos = st.Keyword
+		c.expr(st.Condition)
+		c.emit("call $truthy")
+		c.emit("if")
+		c.stmt(st.Body)
+		if st.ElseBody != nil {
+			c.emit("else")
+			c.stmt(st.ElseBody)
+		}
+		c.emit("end")
+	case *While:
+		c.pos = st.Keyword
+		c.emit("block")
+		c.emit("loop")
+		c.expr(st.Condition)
+		c.emit("call $truthy")
+		c.emit("i32.eqz")
+		c.emit("br_if 1")
+		c.stmt(st.Body)
+		c.emit("br 0")
+		c.emit("end")
+		c.emit("end")
+	case *Return:
+		c.pos = st.Keyword
+		if st.Value == nil {
+			c.emit("global.get $nil")
+		} else {
+			c.expr(st.Value)
+		}
+		c.emit("return")
+	case *Throw:
+		c.unsupported(st.Keyword, "exceptions")
+	case *Try:
+		c.unsupported(st.Keyword, "exceptions")
+	default:
+		panic(fmt.Sprintf("cannot compile %T", s))
+	}
+}
+
+// define declares a variable initialized with the value init pushes.
+func (c *WatCompiler) define(name Token, unassigned bool, init func()) {
+	if len(c.scopes) == 0 {
+		g := c.global(name)
+
+		init()
+		c.emit("global.get %s", g)
+		c.nameArgs(name)
+		c.emit("call $define")
+		c.emit("global.set %s", g)
+
+		return
+	}
+
+	// The variable is declared before the initializer is compiled, since
+	// functions in it may refer to the variable.
+	sc := c.scopes[len(c.scopes)-1]
+	slot := len(sc.vars)
+	sc.vars = append(sc.vars, watVar{unassigned: unassigned})
+
+	c.emit("local.get $env")
+	init()
+	c.emit("i64.store offset=%d", 8+8*slot)
+}
+
+// block compiles stmts in a new scope.
+func (c *WatCompiler) block(stmts []Stmt) {
+	heap := c.beginScope(stmts)
+	if heap {
+		c.emit("local.get $env")
+		c.emit("i32.const %d", watVars(stmts))
+		c.emit("call $env")
+		c.emit("local.set $env")
+	}
+
+	c.stmts(stmts)
+
+	if heap {
+		c.emit("local.get $env")
+		c.emit("i32.load")
+		c.emit("local.set $env")
+	}
+
+	c.endScope()
+}
+
+// function compiles a function declaration and pushes a closure of it.
+func (c *WatCompiler) function(f *Func) {
+	index := len(c.funcs)
+	c.funcs = append(c.funcs, "")
+	c.arities[len(f.Params)] = true
+
+	fn := c.fn
+	c.fn = &watFunc{}
+
+	c.scopes = append(c.scopes, &watScope{heap: len(f.Params)+watVars(f.Body) > 0})
+	sc := c.scopes[len(c.scopes)-1]
+
+	c.emit("local.get $closure")
+	c.emit("i32.load offset=8")
+	if sc.heap {
+		c.emit("i32.const %d", len(f.Params)+watVars(f.Body))
+		c.emit("call $env")
+	}
+	c.emit("local.set $env")
+
+	params := ""
+	for i := range f.Params {
+		sc.vars = append(sc.vars, watVar{})
+		params += fmt.Sprintf(" (param $p%d i64)", i)
+
+		c.emit("local.get $env")
+		c.emit("local.get $p%d", i)
+		c.emit("i64.store offset=%d", 8+8*i)
+	}
+
+	c.stmts(f.Body)
+	c.emit("global.get $nil")
+	c.endScope()
+
+	c.funcs[index] = c.funcText(fmt.Sprintf("(func $f%d (type $fn%d) (param $closure i32)%s (result i64)", index, len(f.Params), params), c.fn)
+	c.fn = fn
+
+	name, n := c.str(f.Name.Lexeme)
+	c.emit("i32.const %d", index)
+	c.emit("i32.const %d", len(f.Params))
+	c.emit("local.get $env")
+	c.emit("i32.const %d", name)
+	c.emit("i32.const %d", n)
+	c.emit("call $closure")
+}
+
+// Expressions
+
+// expr compiles e to instructions pushing its value.
+func (c *WatCompiler) expr(e Expr) {
+	switch ex := e.(type) {
+	case *Literal:
+		c.literal(ex.Value)
+	case *Grouping:
+		c.expr(ex.Expr)
+	case *Variable:
+		c.variable(ex, ex.Name)
+	case *Assign:
+		c.assign(ex, ex.Name, func() { c.expr(ex.Value) })
+	case *Binary:
+		c.binary(ex.Operator, func() { c.expr(ex.Left) }, ex.Right)
+	case *Unary:
+		c.unary(ex)
+	case *Call:
+		c.call(ex)
+	case *Logical:
+		c.expr(ex.Left)
+		c.emit("local.tee $l")
+		c.emit("call $truthy")
+		c.emit("if (result i64)")
+		if ex.Operator.Type == AND {
+			c.expr(ex.Right)
+			c.emit("else")
+			c.emit("local.get $l")
+		} else {
+			c.emit("local.get $l")
+			c.emit("else")
+			c.expr(ex.Right)
+		}
+		c.emit("end")
+	case *Conditional:
+		c.expr(ex.Condition)
+		c.emit("call $truthy")
+		c.emit("if (result i64)")
+		c.expr(ex.Then)
+		c.emit("else")
+		c.expr(ex.Else)
+		c.emit("end")
+	case *Lambda:
+		c.function(ex.Decl)
+	case *Get:
+		c.unsupported(ex.Name, "properties")
+	case *CompoundAssign:
+		op := ex.Operator
+		op.Type = compoundOperators[op.Type]
+
+		c.assign(ex, ex.Name, func() { c.binary(op, func() { c.variable(ex, ex.Name) }, ex.Value) })
+	case *Increment:
+		c.increment(ex)
+	default:
+		panic(fmt.Sprintf("cannot compile %T", e))
+	}
+}
+
+func (c *WatCompiler) literal(v Value) {
+	switch v.Type() {
+	case NilType:
+		c.emit("global.get $nil")
+	case BoolType:
+		c.emit("global.get $%t", v.AsBool())
+	case IntType:
+		if v.IsBigInt() || v.AsInt() < watIntMin || v.AsInt() > watIntMax {
+			c.unsupported(c.pos, "integers of more than 48 bits")
+
+			return
+		}
+
+		c.emit("i64.const %#x", watInt|uint64(v.AsInt())&(1<<48-1))
+	case NumberType:
+		c.number(v.AsNumber())
+	case StringType:
+		c.unsupported(c.pos, "strings")
+	default:
+		panic(fmt.Sprintf("cannot compile a literal of type %s", v.Type()))
+	}
+}
+
+func (c *WatCompiler) number(n float64) {
+	var s string
+
+	switch {
+	case math.IsNaN(n):
+		s = "nan"
+	case math.IsInf(n, 0):
+		s = strings.ToLower(strconv.FormatFloat(n, 'g', -1, 64))
+	default:
+		s = strconv.FormatFloat(n, 'g', -1, 64)
+	}
+
+	c.emit("f64.const %s", s)
+	c.emit("i64.reinterpret_f64")
+}
+
+// variable compiles reading the variable name that expr refers to.
+func (c *WatCompiler) variable(expr Expr, name Token) {
+	l, ok := c.interp.locals[expr]
+	if !ok {
+		c.emit("global.get %s", c.global(name))
+		c.nameArgs(name)
+		c.emit("call $global")
+
+		return
+	}
+
+	c.envAt(l.depth)
+	c.emit("i64.load offset=%d", 8+8*l.slot)
+
+	if c.scopes[len(c.scopes)-1-l.depth].vars[l.slot].unassigned {
+		c.nameArgs(name)
+		c.emit("call $assigned")
+	}
+}
+
+// assign compiles assigning the value val pushes to the variable name that
+// expr refers to.
+func (c *WatCompiler) assign(expr Expr, name Token, val func()) {
+	l, ok := c.interp.locals[expr]
+	if !ok {
+		g := c.global(name)
+
+		val()
+		c.emit("global.get %s", g)
+		c.nameArgs(name)
+		c.emit("call $defined")
+		c.emit("local.tee $v")
+		c.emit("global.set %s", g)
+		c.emit("local.get $v")
+
+		return
+	}
+
+	c.envAt(l.depth)
+	val()
+	c.emit("local.tee $v")
+	c.emit("i64.store offset=%d", 8+8*l.slot)
+	c.emit("local.get $v")
+}
+
+// binary compiles the operator op applied to the value left pushes and right.
+func (c *WatCompiler) binary(op Token, left func(), right Expr) {
+	c.pos = op
+
+	switch op.Type {
+	case COMMA:
+		left()
+		c.emit("drop")
+		c.expr(right)
+	case EQUAL_EQUAL, BANG_EQUAL:
+		left()
+		c.expr(right)
+		c.emit("call $eq")
+		if op.Type == BANG_EQUAL {
+			c.emit("call $not")
+		}
+	default:
+		fn, ok := watOperators[op.Type]
+		if !ok {
+			c.unsupported(op, fmt.Sprintf("the `%s` operator", op.Lexeme))
+
+			return
+		}
+
+		left()
+		c.expr(right)
+		c.emit("i32.const %d", op.Line)
+		c.emit("call %s", fn)
+	}
+}
+
+func (c *WatCompiler) unary(u *Unary) {
+	c.pos = u.Operator
+
+	switch u.Operator.Type {
+	case MINUS:
+		c.expr(u.Right)
+		c.emit("i32.const %d", u.Operator.Line)
+		c.emit("call $neg")
+	case BANG:
+		c.expr(u.Right)
+		c.emit("call $not")
+	default:
+		c.unsupported(u.Operator, "strings")
+	}
+}
+
+func (c *WatCompiler) increment(i *Increment) {
+	c.pos = i.Operator
+
+	fn, lexeme := "$inc", watPlusPlus
+	if i.Operator.Type == MINUS_MINUS {
+		fn, lexeme = "$dec", watMinusMin
+	}
+
+	c.assign(i, i.Name, func() {
+		c.variable(i, i.Name)
+		c.emit("local.tee $o")
+		c.emit("i32.const %d", i.Operator.Line)
+		c.emit("i32.const %d", lexeme)
+		c.emit("call %s", fn)
+	})
+
+	if i.Postfix {
+		c.emit("drop")
+		c.emit("local.get $o")
+	}
+}
+
+// call compiles a call. The callee is checked before the arguments are
+// evaluated.
+func (c *WatCompiler) call(call *Call) {
+	c.pos = call.Paren
+	c.arities[len(call.Args)] = true
+
+	callee := c.fn.calls
+	c.fn.calls++
+
+	c.expr(call.Callee)
+	c.emit("i32.const %d", len(call.Args))
+	c.emit("i32.const %d", call.Paren.Line)
+	c.emit("call $callee")
+	c.emit("local.tee $c%d", callee)
+
+	for _, arg := range call.Args {
+		c.expr(arg)
+	}
+
+	c.emit("local.get $c%d", callee)
+	c.emit("i32.load")
+	c.emit("call_indirect (type $fn%d)", len(call.Args))
+}
+
+// Scopes and names
+
+// beginScope enters the scope of a block declaring the variables in stmts,
+// reporting whether it needs an environment.
+func (c *WatCompiler) beginScope(stmts []Stmt) bool {
+	c.scopes = append(c.scopes, &watScope{heap: watVars(stmts) > 0})
+
+	return c.scopes[len(c.scopes)-1].heap
+}
+
+func (c *WatCompiler) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// watVars returns the number of variables stmts declare in their scope.
+func watVars(stmts []Stmt) int {
+	n := 0
+
+	for _, s := range stmts {
+		switch s.(type) {
+		case *Var, *Func:
+			n++
+		}
+	}
+
+	return n
+}
+
+// envAt pushes the environment of the scope depth scopes above the current
+// one, skipping the scopes without an environment.
+func (c *WatCompiler) envAt(depth int) {
+	c.emit("local.get $env")
+
+	for _, sc := range c.scopes[len(c.scopes)-depth:] {
+		if sc.heap {
+			c.emit("i32.load")
+		}
+	}
+}
+
+// global returns the WebAssembly global holding the global variable name.
+func (c *WatCompiler) global(name Token) string {
+	if g, ok := c.globals[name.Lexeme]; ok {
+		return g
+	}
+
+	if _, err := c.natives.Get(name); err == nil {
+		c.unsupported(name, "native functions")
+	}
+
+	ident := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return r
+		}
+
+		return -1
+	}, name.Lexeme)
+
+	g := fmt.Sprintf("$g%d_%s", len(c.globalDecls), ident)
+	c.globals[name.Lexeme] = g
+	c.globalDecls = append(c.globalDecls, g)
+
+	return g
+}
+
+// nameArgs pushes the line of name and the address and length of its lexeme,
+// for the errors reported about the variable.
+func (c *WatCompiler) nameArgs(name Token) {
+	addr, n := c.str(name.Lexeme)
+
+	c.emit("i32.const %d", name.Line)
+	c.emit("i32.const %d", addr)
+	c.emit("i32.const %d", n)
+}
+
+// str returns the address and the length of s in the data of the module.
+func (c *WatCompiler) str(s string) (int, int) {
+	if addr, ok := c.strs[s]; ok {
+		return addr, len(s)
+	}
+
+	addr := watDataStart + c.data.Len()
+	c.strs[s] = addr
+	c.data.WriteString(s)
+
+	return addr, len(s)
+}
+
+// watString escapes s for a string of the text format.
+func watString(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if ch := s[i]; ch >= 0x20 && ch < 0x7f && ch != '"' && ch != '\\' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "\\%02x", ch)
+		}
+	}
+
+	return b.String()
+}
+
+func (c *WatCompiler) unsupported(t Token, what string) {
+	if c.err == nil {
+		c.err = genError(t, Unsupported, fmt.Sprintf("The WebAssembly backend does not support %s.", what))
+	}
+}
+
+// emit appends an instruction to the current function, indented by the
+// blocks it is in.
+func (c *WatCompiler) emit(format string, args ...interface{}) {
+	ins := fmt.Sprintf(format, args...)
+	if ins == "end" || ins == "else" {
+		c.fn.depth--
+	}
+
+	c.fn.code = append(c.fn.code, strings.Repeat("  ", c.fn.depth)+ins)
+
+	if ins == "else" || strings.HasPrefix(ins, "block") || strings.HasPrefix(ins, "loop") || strings.HasPrefix(ins, "if") {
+		c.fn.depth++
+	}
+}
+
+// watArithmetic returns the runtime functions of the binary operators on
+// numbers. The operators with an integer instruction return an integer when
+// both operands are integers, computing the result in floating point too to
+// check that it fits.
+func watArithmetic() string {
+	var b strings.Builder
+
+	for _, op := range []struct {
+		name, ins string
+		// The instruction on integers, if the result can be one.
+		intIns string
+		code   int
+		// The instruction making a value of the result.
+		res string
+	}{
+		{"$add", "f64.add", "i64.add", watErrPlus, "i64.reinterpret_f64"},
+		{"$sub", "f64.sub", "i64.sub", watErrOperands, "i64.reinterpret_f64"},
+		{"$mul", "f64.mul", "i64.mul", watErrOperands, "i64.reinterpret_f64"},
+		{"$div", "f64.div", "", watErrOperands, "i64.reinterpret_f64"},
+		{"$gt", "f64.gt", "", watErrOperands, "call $bool"},
+		{"$ge", "f64.ge", "", watErrOperands, "call $bool"},
+		{"$lt", "f64.lt", "", watErrOperands, "call $bool"},
+		{"$le", "f64.le", "", watErrOperands, "call $bool"},
+	} {
+		fmt.Fprintf(&b, `  (func %s (param $a i64) (param $b i64) (param $line i32) (result i64)
+    local.get $a
+    local.get $b
+    i32.const %d
+    local.get $line
+    call $nums
+`, op.name, op.code)
+
+		if op.intIns != "" {
+			fmt.Fprintf(&b, `    local.get $a
+    call $is_int
+    local.get $b
+    call $is_int
+    i32.and
+    if
+      local.get $a
+      call $f64
+      local.get $b
+      call $f64
+      %s
+      local.get $a
+      call $int_val
+      local.get $b
+      call $int_val
+      %s
+      local.get $line
+      call $int
+      return
+    end
+`, op.ins, op.intIns)
+		}
+
+		fmt.Fprintf(&b, `    local.get $a
+    call $f64
+    local.get $b
+    call $f64
+    %s
+    %s)
+`, op.ins, op.res)
+	}
+
+	return b.String()
+}
+
+// watRuntime holds the functions compiled code calls. Closures are made of the
+// table index, the arity, the environment and the name of their function, and
+// the errors are reported with the codes WasmHost.Error describes.
+const watRuntime = `  (func $fail (param $code i32) (param $line i32) (param $a i32) (param $b i32)
+    local.get $code
+    local.get $line
+    local.get $a
+    local.get $b
+    call $error
+    unreachable)
+  (func $alloc (param $size i32) (result i32)
+    (local $p i32)
+    global.get $heap
+    local.set $p
+    local.get $p
+    local.get $size
+    i32.add
+    i32.const 7
+    i32.add
+    i32.const -8
+    i32.and
+    global.set $heap
+    block
+      global.get $heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.le_u
+      br_if 0
+      global.get $heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.sub
+      i32.const 65535
+      i32.add
+      i32.const 16
+      i32.shr_u
+      memory.grow
+      i32.const -1
+      i32.ne
+      br_if 0
+      unreachable
+    end
+    local.get $p)
+  (func $env (param $enclosing i32) (param $slots i32) (result i32)
+    (local $p i32)
+    local.get $slots
+    i32.const 8
+    i32.mul
+    i32.const 8
+    i32.add
+    call $alloc
+    local.tee $p
+    local.get $enclosing
+    i32.store
+    local.get $p)
+  (func $closure (param $index i32) (param $arity i32) (param $env i32) (param $name i32) (param $len i32) (result i64)
+    (local $p i32)
+    i32.const 20
+    call $alloc
+    local.tee $p
+    local.get $index
+    i32.store
+    local.get $p
+    local.get $arity
+    i32.store offset=4
+    local.get $p
+    local.get $env
+    i32.store offset=8
+    local.get $p
+    local.get $name
+    i32.store offset=12
+    local.get $p
+    local.get $len
+    i32.store offset=16
+    local.get $p
+    i64.extend_i32_u
+    i64.const 0xfffc000000000000
+    i64.or)
+  (func $callee (param $v i64) (param $argc i32) (param $line i32) (result i32)
+    (local $p i32)
+    block
+      local.get $v
+      i64.const 0xfffc000000000000
+      i64.and
+      i64.const 0xfffc000000000000
+      i64.eq
+      br_if 0
+      i32.const 4
+      local.get $line
+      i32.const 0
+      i32.const 0
+      call $fail
+    end
+    local.get $v
+    i32.wrap_i64
+    local.set $p
+    block
+      local.get $p
+      i32.load offset=4
+      local.get $argc
+      i32.eq
+      br_if 0
+      i32.const 5
+      local.get $line
+      local.get $p
+      i32.load offset=4
+      local.get $argc
+      call $fail
+    end
+    local.get $p)
+  (func $is_num (param $v i64) (result i32)
+    local.get $v
+    i64.const 0x7ffc000000000000
+    i64.and
+    i64.const 0x7ffc000000000000
+    i64.ne
+    local.get $v
+    call $is_int
+    i32.or)
+  (func $is_int (param $v i64) (result i32)
+    local.get $v
+    i64.const 48
+    i64.shr_u
+    i64.const 0x7ffd
+    i64.eq)
+  (func $int_val (param $v i64) (result i64)
+    local.get $v
+    i64.const 16
+    i64.shl
+    i64.const 16
+    i64.shr_s)
+  (func $f64 (param $v i64) (result f64)
+    local.get $v
+    call $is_int
+    if (result f64)
+      local.get $v
+      call $int_val
+      f64.convert_i64_s
+    else
+      local.get $v
+      f64.reinterpret_i64
+    end)
+  (func $int (param $f f64) (param $i i64) (param $line i32) (result i64)
+    block
+      local.get $f
+      f64.const -140737488355328
+      f64.ge
+      local.get $f
+      f64.const 140737488355328
+      f64.lt
+      i32.and
+      br_if 0
+      i32.const 9
+      local.get $line
+      i32.const 0
+      i32.const 0
+      call $fail
+    end
+    local.get $i
+    i64.const 0xffffffffffff
+    i64.and
+    i64.const 0x7ffd000000000000
+    i64.or)
+  (func $nums (param $a i64) (param $b i64) (param $code i32) (param $line i32)
+    block
+      local.get $a
+      call $is_num
+      local.get $b
+      call $is_num
+      i32.and
+      br_if 0
+      local.get $code
+      local.get $line
+      i32.const 0
+      i32.const 0
+      call $fail
+    end)
+  (func $num (param $v i64) (param $line i32) (param $op i32) (param $len i32) (result i32)
+    block
+      local.get $v
+      call $is_num
+      br_if 0
+      i32.const 3
+      local.get $line
+      local.get $op
+      local.get $len
+      call $fail
+    end
+    local.get $v
+    call $is_int)
+  (func $neg (param $v i64) (param $line i32) (result i64)
+    local.get $v
+    local.get $line
+    i32.const 16
+    i32.const 1
+    call $num
+    if (result i64)
+      local.get $v
+      call $f64
+      f64.neg
+      i64.const 0
+      local.get $v
+      call $int_val
+      i64.sub
+      local.get $line
+      call $int
+    else
+      local.get $v
+      f64.reinterpret_i64
+      f64.neg
+      i64.reinterpret_f64
+    end)
+  (func $inc (param $v i64) (param $line i32) (param $op i32) (result i64)
+    local.get $v
+    local.get $line
+    local.get $op
+    i32.const 2
+    call $num
+    if (result i64)
+      local.get $v
+      call $f64
+      f64.const 1
+      f64.add
+      local.get $v
+      call $int_val
+      i64.const 1
+      i64.add
+      local.get $line
+      call $int
+    else
+      local.get $v
+      f64.reinterpret_i64
+      f64.const 1
+      f64.add
+      i64.reinterpret_f64
+    end)
+  (func $dec (param $v i64) (param $line i32) (param $op i32) (result i64)
+    local.get $v
+    local.get $line
+    local.get $op
+    i32.const 2
+    call $num
+    if (result i64)
+      local.get $v
+      call $f64
+      f64.const 1
+      f64.sub
+      local.get $v
+      call $int_val
+      i64.const 1
+      i64.sub
+      local.get $line
+      call $int
+    else
+      local.get $v
+      f64.reinterpret_i64
+      f64.const 1
+      f64.sub
+      i64.reinterpret_f64
+    end)
+  (func $bool (param $c i32) (result i64)
+    global.get $true
+    global.get $false
+    local.get $c
+    select)
+  (func $truthy (param $v i64) (result i32)
+    local.get $v
+    global.get $nil
+    i64.ne
+    local.get $v
+    global.get $false
+    i64.ne
+    i32.and)
+  (func $not (param $v i64) (result i64)
+    local.get $v
+    call $truthy
+    i32.eqz
+    call $bool)
+  (func $eq (param $a i64) (param $b i64) (result i64)
+    local.get $a
+    call $is_num
+    local.get $b
+    call $is_num
+    i32.and
+    if (result i32)
+      local.get $a
+      call $f64
+      local.get $b
+      call $f64
+      f64.eq
+    else
+      local.get $a
+      local.get $b
+      i64.eq
+    end
+    call $bool)
+  (func $assigned (param $v i64) (param $line i32) (param $name i32) (param $len i32) (result i64)
+    block
+      local.get $v
+      global.get $unset
+      i64.ne
+      br_if 0
+      i32.const 7
+      local.get $line
+      local.get $name
+      local.get $len
+      call $fail
+    end
+    local.get $v)
+  (func $global (param $v i64) (param $line i32) (param $name i32) (param $len i32) (result i64)
+    block
+      local.get $v
+      global.get $undef
+      i64.ne
+      br_if 0
+      i32.const 6
+      local.get $line
+      local.get $name
+      local.get $len
+      call $fail
+    end
+    local.get $v
+    local.get $line
+    local.get $name
+    local.get $len
+    call $assigned)
+  (func $defined (param $v i64) (param $old i64) (param $line i32) (param $name i32) (param $len i32) (result i64)
+    block
+      local.get $old
+      global.get $undef
+      i64.ne
+      br_if 0
+      i32.const 6
+      local.get $line
+      local.get $name
+      local.get $len
+      call $fail
+    end
+    local.get $v)
+  (func $define (param $v i64) (param $old i64) (param $line i32) (param $name i32) (param $len i32) (result i64)
+    block
+      local.get $old
+      global.get $undef
+      i64.eq
+      br_if 0
+      i32.const 8
+      local.get $line
+      local.get $name
+      local.get $len
+      call $fail
+    end
+    local.get $v)
+`
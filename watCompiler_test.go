@@ -0,0 +1,242 @@
+package golox_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/agayev169/golox"
+)
+
+// TestWatCompiler compiles the conformance tests within the subset the
+// WatCompiler supports and checks that running them with wazero behaves like
+// the interpreter.
+func TestWatCompiler(t *testing.T) {
+	ran := 0
+
+	forEachConformanceFile(t, func(t *testing.T, path string, src []byte) {
+		stmts, interp, lerr := parseAndResolve(src)
+		if lerr != nil {
+			return
+		}
+
+		module, lerr := golox.NewWatCompiler(interp).Compile(golox.NewOptimizer().Optimize(stmts))
+		if lerr != nil {
+			if lerr.Number != golox.Unsupported {
+				t.Errorf("Failed to compile %s: %v", path, lerr)
+			}
+
+			return
+		}
+
+		ran++
+
+		res := golox.RunTestSourceWith(path, src, func(src []byte, out *bytes.Buffer) (compileErr, runtimeErr *golox.LoxError) {
+			return nil, runWasm(t, module, out)
+		})
+
+		for _, f := range res.Failures {
+			t.Error(f)
+		}
+	})
+
+	if ran == 0 {
+		t.Error("No conformance test is within the supported subset")
+	}
+}
+
+// TestWatCompilerWat2wasm checks the modules of the conformance tests with
+// wat2wasm from the WebAssembly Binary Toolkit, when it is installed, since
+// the other tests only assemble them with assembleWat.
+func TestWatCompilerWat2wasm(t *testing.T) {
+	wat2wasm, err := exec.LookPath("wat2wasm")
+	if err != nil {
+		t.Skip("wat2wasm is not installed")
+	}
+
+	dir, err := os.MkdirTemp("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	forEachConformanceFile(t, func(t *testing.T, path string, src []byte) {
+		stmts, interp, lerr := parseAndResolve(src)
+		if lerr != nil {
+			return
+		}
+
+		module, lerr := golox.NewWatCompiler(interp).Compile(golox.NewOptimizer().Optimize(stmts))
+		if lerr != nil {
+			return
+		}
+
+		wat := filepath.Join(dir, "module.wat")
+		if err := os.WriteFile(wat, module, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command(wat2wasm, wat, "-o", filepath.Join(dir, "module.wasm")).CombinedOutput()
+		if err != nil {
+			t.Errorf("wat2wasm rejected the module of %s: %v\n%s", path, err, out)
+		}
+	})
+}
+
+// TestWatCompilerClosures checks that closures share the variables they
+// capture with their enclosing function and with each other.
+func TestWatCompilerClosures(t *testing.T) {
+	src := `fun counter(start) {
+  var n = start;
+  fun inc() { n = n + 1; return n; }
+  return inc;
+}
+
+fun makeAdder(a) {
+  fun adder(b) {
+    fun add(c) { return a + b + c; }
+    return add;
+  }
+  return adder;
+}
+
+var c1 = counter(0);
+var c2 = counter(10);
+print c1();
+print c1();
+print c2();
+print c1();
+print makeAdder(1)(20)(300);
+
+var shared;
+var read;
+{
+  var x = 1;
+  fun set(v) { x = v; }
+  fun get() { return x; }
+  shared = set;
+  read = get;
+}
+shared(42);
+print read();
+`
+
+	stmts, interp, lerr := parseAndResolve([]byte(src))
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	module, lerr := golox.NewWatCompiler(interp).Compile(stmts)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+
+	var out bytes.Buffer
+	if lerr = runWasm(t, module, &out); lerr != nil {
+		t.Fatalf("Unexpected error: %v", lerr)
+	}
+
+	if expected := "1\n2\n11\n3\n321\n42\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWatCompilerUnsupported(t *testing.T) {
+	for _, src := range []string{
+		`print "a";`,
+		`var a = 1; print a.b;`,
+		`throw 1;`,
+		`print 7 % 2;`,
+		`print 100000000000000000000;`,
+		`print 9007199254740993;`,
+		`print 9223372036854775807;`,
+		`print clock();`,
+	} {
+		stmts, interp, lerr := parseAndResolve([]byte(src))
+		if lerr != nil {
+			t.Fatalf("%s: %v", src, lerr)
+		}
+
+		if _, lerr = golox.NewWatCompiler(interp).Compile(stmts); lerr == nil || lerr.Number != golox.Unsupported {
+			t.Errorf("%s: expected an unsupported feature error, got %v", src, lerr)
+		}
+	}
+}
+
+// TestWatCompilerOverflow checks that integer results that do not fit in 48
+// bits are reported instead of rounded.
+func TestWatCompilerOverflow(t *testing.T) {
+	for _, src := range []string{
+		"var x = 140737488355327;\nprint x + 1;",
+		"var x = -140737488355327 - 1;\nprint -x;",
+		"var x = 16777216;\nprint x * x;",
+		"var x = 140737488355327;\nx++;",
+	} {
+		stmts, interp, lerr := parseAndResolve([]byte(src))
+		if lerr != nil {
+			t.Fatalf("%s: %v", src, lerr)
+		}
+
+		module, lerr := golox.NewWatCompiler(interp).Compile(stmts)
+		if lerr != nil {
+			t.Fatalf("%s: %v", src, lerr)
+		}
+
+		var out bytes.Buffer
+		if lerr = runWasm(t, module, &out); lerr == nil || lerr.Number != golox.Unsupported || lerr.Line != 2 {
+			t.Errorf("%s: expected an unsupported feature error on line 2, got %v", src, lerr)
+		}
+	}
+}
+
+// runWasm runs the module in the text format src and returns the runtime
+// error it reported, if any.
+func runWasm(t *testing.T, src []byte, out *bytes.Buffer) *golox.LoxError {
+	bin, err := assembleWat(src)
+	if err != nil {
+		t.Fatalf("Failed to assemble the module: %v\n%s", err, src)
+	}
+
+	ctx := context.Background()
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	host := golox.NewWasmHost(out)
+	memory := func(m api.Module) []byte {
+		mem, _ := m.Memory().Read(0, m.Memory().Size())
+
+		return mem
+	}
+
+	_, err = r.NewHostModuleBuilder("lox").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, v uint64) { host.Print(memory(m), v) }).
+		Export("print").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, code, line, a, b uint32) {
+			host.Error(memory(m), code, line, a, b)
+		}).
+		Export("error").
+		Instantiate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := r.Instantiate(ctx, bin)
+	if err != nil {
+		t.Fatalf("Failed to instantiate the module: %v\n%s", err, src)
+	}
+
+	if _, err = m.ExportedFunction("main").Call(ctx); err != nil && host.Err == nil {
+		t.Fatalf("The module trapped: %v", err)
+	}
+
+	return host.Err
+}